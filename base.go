@@ -1,7 +1,9 @@
 package streams
 
 import (
+	"context"
 	"io"
+	"iter"
 )
 
 type Stream[T any] interface {
@@ -19,9 +21,22 @@ func NewBaseStream[T any](stream Stream[T]) BaseStream[T] {
 	return BaseStream[T]{Stream: avoidNil(stream)}
 }
 
+func (c BaseStream[T]) RecvContext(ctx context.Context) (T, error) {
+	return RecvContext(ctx, c.Stream)
+}
+
+func (c BaseStream[T]) Close() error {
+	return Close(c.Stream)
+}
+
 func (c BaseStream[T]) Consume(handle func(T) error) error {
+	return c.ConsumeContext(context.Background(), handle)
+}
+
+// ConsumeContext 同Consume，ctx取消时返回ctx.Err()
+func (c BaseStream[T]) ConsumeContext(ctx context.Context, handle func(T) error) error {
 	for {
-		v, err := c.Recv()
+		v, err := c.RecvContext(ctx)
 		if err == io.EOF {
 			return nil
 		} else if err != nil {
@@ -47,45 +62,85 @@ func avoidNils[T any](ss []Stream[T]) []Stream[T] {
 	return ss
 }
 
-// func (c BaseStream[T]) Iter() iter.Seq[T] {
-// 	if c.Stream == nil {
-// 		return func(yield func(T) bool) {}
-// 	}
-// 	return func(yield func(T) bool) {
-// 		for {
-// 			v, err := c.Recv()
-// 			if err != nil {
-// 				break
-// 			}
-// 			if !yield(v) {
-// 				return
-// 			}
-// 		}
-// 	}
-// }
+func (c BaseStream[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			v, err := c.Recv()
+			if err != nil {
+				return
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func (c BaseStream[T]) Iter2() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := c.Recv()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
 
 // Iter 返回一个迭代器，遇到错误或者流结束时返回
-// 注意：这会忽略流中的错误，如果需要处理错误，请使用Consume
-// func Iter[T any](stream Stream[T]) iter.Seq[T] {
-// 	return NewBaseStream(stream).Iter()
-// }
+// 注意：这会忽略流中的错误，如果需要处理错误，请使用Iter2或Consume
+func Iter[T any](stream Stream[T]) iter.Seq[T] {
+	return NewBaseStream(stream).Iter()
+}
+
+// Iter2 返回一个带错误的迭代器，流正常结束时迭代结束，遇到非io.EOF的错误时会以(零值, err)迭代一次再结束
+// 提前break不会关闭流，流可以继续被消费
+func Iter2[T any](stream Stream[T]) iter.Seq2[T, error] {
+	return NewBaseStream(stream).Iter2()
+}
 
 // Consume 阻塞接收流中的数据，并对每个数据调用handle函数
 func Consume[T any](stream Stream[T], handle func(T) error) error {
 	return NewBaseStream(stream).Consume(handle)
 }
 
+// ConsumeContext 同Consume，ctx取消时返回ctx.Err()
+func ConsumeContext[T any](ctx context.Context, stream Stream[T], handle func(T) error) error {
+	return NewBaseStream(stream).ConsumeContext(ctx, handle)
+}
+
 // ToChan 将流中的数据转换为channel，如果流中没有数据或者异常，则channel会立即关闭
 // 注意事项：
 // 1. 这会忽略流中的错误，如果需要处理错误，请使用Consume
-// 2. 调用方需要确保channel被消费完毕，否则会导致goroutine泄漏
+// 2. 调用方需要确保channel被消费完毕，否则会导致goroutine泄漏，无法保证时请使用ToChanContext
 func ToChan[T any](stream Stream[T]) <-chan T {
+	return ToChanContext(context.Background(), stream)
+}
+
+// ToChanContext 同ToChan，ctx取消后内部goroutine会停止读取、关闭上游流并关闭channel，即使channel没有被消费完毕也不会泄漏
+func ToChanContext[T any](ctx context.Context, stream Stream[T]) <-chan T {
 	ch := make(chan T, 16)
 	go func() {
 		defer close(ch)
-		Consume(stream, func(v T) error {
-			ch <- v
-			return nil
+		defer func() {
+			if ctx.Err() != nil {
+				Close(stream)
+			}
+		}()
+		ConsumeContext(ctx, stream, func(v T) error {
+			select {
+			case ch <- v:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		})
 	}()
 	return ch
@@ -0,0 +1,56 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	t.Run("collect all", func(t *testing.T) {
+		got := slices.Collect(Iter(FromSlice([]int{1, 2, 3})))
+		if !slices.Equal(got, []int{1, 2, 3}) {
+			t.Fatalf("got %v, want [1 2 3]", got)
+		}
+	})
+
+	t.Run("break keeps the rest", func(t *testing.T) {
+		s := FromSlice([]int{1, 2, 3})
+		for v := range Iter(s) {
+			if v == 2 {
+				break
+			}
+		}
+		expectStream(t, s, []int{3}, io.EOF)
+	})
+
+	t.Run("error is ignored", func(t *testing.T) {
+		got := slices.Collect(Iter(Concat(FromSlice([]int{1}), FromErr[int](errors.New("boom")))))
+		if !slices.Equal(got, []int{1}) {
+			t.Fatalf("got %v, want [1]", got)
+		}
+	})
+}
+
+func TestIter2(t *testing.T) {
+	boom := errors.New("boom")
+	var got []int
+	var gotErr error
+	for v, err := range Iter2(Concat(FromSlice([]int{1, 2}), FromErr[int](boom))) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 2}) || gotErr != boom {
+		t.Fatalf("got %v, %v; want [1 2], boom", got, gotErr)
+	}
+
+	for _, err := range Iter2(FromSlice([]int{1})) {
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package streams
+
+// SubscribeMode 订阅Broadcaster时的起始位置
+type SubscribeMode int
+
+const (
+	SubscribeLive   SubscribeMode = iota // 从最新的位置开始，只接收订阅之后才读到的数据
+	SubscribeReplay                      // 从头开始重放所有历史数据，再接着接收新数据
+)
+
+// Broadcaster 将一条流广播给动态增减的订阅者，和Fork不同，订阅者可以在任意时刻加入或离开
+// 注意事项：
+// 1. 为了支持重放，Broadcaster会缓存上游的全部数据，直到Broadcaster被关闭
+// 2. 和其他算子一样是惰性求值的，只有订阅者消费时才会读取上游，没有订阅者时上游会暂停。如果需要上游持续推进，可以先用WithBuffer包装上游
+// 3. 所有订阅者都离开时不会关闭上游，需要调用Broadcaster.Close
+type Broadcaster[T any] struct {
+	cache *streamCache[T]
+}
+
+// NewBroadcaster 创建一个Broadcaster，opts中的WithMaxLag可以限制订阅者之间的差距
+func NewBroadcaster[T any](src Stream[T], opts ...ForkOption) *Broadcaster[T] {
+	cache := newCache(src, opts...)
+	cache.keepAll = true
+	return &Broadcaster[T]{cache: cache}
+}
+
+// Subscribe 新增一个订阅者，返回的流被Close即取消订阅
+// Broadcaster关闭之后再订阅，返回的流会直接返回ErrClosed
+func (b *Broadcaster[T]) Subscribe(mode SubscribeMode) Stream[T] {
+	c := b.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	index := c.base + len(c.buf)
+	if mode == SubscribeReplay {
+		index = c.base
+	}
+	return c.attach(index)
+}
+
+// Unsubscribe 取消订阅，释放订阅者的位置，等同于Close(s)
+func (b *Broadcaster[T]) Unsubscribe(s Stream[T]) error {
+	return Close(s)
+}
+
+// Close 关闭所有订阅者并关闭上游
+func (b *Broadcaster[T]) Close() error {
+	return b.cache.shutdown()
+}
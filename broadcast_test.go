@@ -0,0 +1,65 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBroadcaster(t *testing.T) {
+	t.Run("replay and live", func(t *testing.T) {
+		b := NewBroadcaster(FromSlice([]int{1, 2, 3, 4}))
+		first := b.Subscribe(SubscribeLive)
+		first.Recv()
+		first.Recv()
+
+		replay := b.Subscribe(SubscribeReplay)
+		live := b.Subscribe(SubscribeLive)
+		expectStream(t, first, []int{3, 4}, io.EOF)
+		expectStream(t, replay, []int{1, 2, 3, 4}, io.EOF)
+		expectStream(t, live, []int{3, 4}, io.EOF)
+	})
+
+	t.Run("late subscriber after upstream finished", func(t *testing.T) {
+		b := NewBroadcaster(FromSlice([]int{1, 2}))
+		expectStream(t, b.Subscribe(SubscribeLive), []int{1, 2}, io.EOF)
+		expectStream(t, b.Subscribe(SubscribeReplay), []int{1, 2}, io.EOF)
+		expectStream(t, b.Subscribe(SubscribeLive), []int{}, io.EOF)
+	})
+
+	t.Run("unsubscribe keeps upstream open", func(t *testing.T) {
+		src, closed := trackClose(FromSlice([]int{1, 2, 3}))
+		b := NewBroadcaster(src)
+		s := b.Subscribe(SubscribeLive)
+		s.Recv()
+		b.Unsubscribe(s)
+		if _, err := s.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+		if closed.Load() != 0 {
+			t.Fatal("upstream closed after the last subscriber left")
+		}
+		// 重连的订阅者接着已有的进度继续
+		expectStream(t, b.Subscribe(SubscribeReplay), []int{1, 2, 3}, io.EOF)
+
+		live := b.Subscribe(SubscribeLive)
+		b.Close()
+		if closed.Load() != 1 {
+			t.Fatalf("upstream closed %d times, want 1", closed.Load())
+		}
+		if _, err := live.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+		if _, err := b.Subscribe(SubscribeReplay).Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("max lag", func(t *testing.T) {
+		b := NewBroadcaster(FromSlice([]int{1, 2, 3, 4}), WithMaxLag(1, LagError))
+		slow := b.Subscribe(SubscribeLive)
+		fast := b.Subscribe(SubscribeLive)
+		expectStream(t, fast, []int{1, 2, 3, 4}, io.EOF)
+		expectStream(t, slow, []int{}, ErrLagged)
+	})
+}
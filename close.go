@@ -0,0 +1,53 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrClosed 流已经被下游关闭
+var ErrClosed = errors.New("streams: stream closed")
+
+// Close 通知上游下游已经不再消费，流实现了io.Closer时调用其Close，否则什么都不做
+// 内置的算子都实现了io.Closer，关闭管道的末端会沿着管道逐级关闭上游，从而释放rpc流、goroutine和缓存
+func Close[T any](stream Stream[T]) error {
+	if c, ok := stream.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// closeAll 关闭所有流，返回所有的错误
+func closeAll[T any](streams []Stream[T]) error {
+	var errs []error
+	for _, s := range streams {
+		errs = append(errs, Close(s))
+	}
+	return errors.Join(errs...)
+}
+
+type closerStream[T any] struct {
+	Stream[T]
+	once  sync.Once
+	close func() error
+}
+
+// OnClose 在流被关闭时调用f，然后继续关闭上游。f只会被调用一次
+// 用于给rpc流等外部流挂上释放资源的逻辑，比如取消rpc的ctx
+func OnClose[T any](src Stream[T], f func() error) Stream[T] {
+	return &closerStream[T]{Stream: avoidNil(src), close: f}
+}
+
+func (s *closerStream[T]) RecvContext(ctx context.Context) (T, error) {
+	return RecvContext(ctx, s.Stream)
+}
+
+func (s *closerStream[T]) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = errors.Join(s.close(), Close(s.Stream))
+	})
+	return err
+}
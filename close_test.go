@@ -0,0 +1,148 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// trackClose 返回一个被关闭时会计数的流
+func trackClose[T any](src Stream[T]) (Stream[T], *atomic.Int32) {
+	var closed atomic.Int32
+	return OnClose(src, func() error {
+		closed.Add(1)
+		return nil
+	}), &closed
+}
+
+func TestClose(t *testing.T) {
+	t.Run("operators forward close", func(t *testing.T) {
+		ops := map[string]func(Stream[string]) Stream[string]{
+			"Map":           func(s Stream[string]) Stream[string] { return Map(s, func(v string) string { return v }) },
+			"Filter":        func(s Stream[string]) Stream[string] { return Filter(s, func(string) bool { return true }) },
+			"Concat":        func(s Stream[string]) Stream[string] { return Concat(FromSlice([]string{"a"}), s) },
+			"FirstNonEmpty": func(s Stream[string]) Stream[string] { return FirstNonEmpty(s, Empty[string]()) },
+			"Substitute":    func(s Stream[string]) Stream[string] { return SubstituteStream(s, nil) },
+			"TakeWhile":     func(s Stream[string]) Stream[string] { return TakeWhile(s, func(string) bool { return true }) },
+			"SkipN":         func(s Stream[string]) Stream[string] { return SkipN(s, 1) },
+			"WithLog":       func(s Stream[string]) Stream[string] { return WithLog(s, "key", func(string) {}) },
+			"ToSafe":        func(s Stream[string]) Stream[string] { return ToSafe(s) },
+			"RemoveTokens":  func(s Stream[string]) Stream[string] { return NewRemoveTokensStream(s, []string{"<x>"}) },
+			"StringReader":  func(s Stream[string]) Stream[string] { return NewStringReader(s).ToLineReader() },
+			"ThrottleMerge": func(s Stream[string]) Stream[string] { return ThrottleMerge(s, mergeStrings, time.Millisecond) },
+		}
+		for name, op := range ops {
+			t.Run(name, func(t *testing.T) {
+				src, closed := trackClose(FromSlice([]string{"a", "b"}))
+				if err := Close(op(src)); err != nil {
+					t.Fatalf("Close() = %v", err)
+				}
+				if closed.Load() != 1 {
+					t.Fatalf("upstream closed %d times, want 1", closed.Load())
+				}
+			})
+		}
+	})
+
+	t.Run("demux closes upstream after all outputs closed", func(t *testing.T) {
+		src, closed := trackClose(FromSlice([]string{"a<A>b"}))
+		demux := NewLabelStream(src, []SLabel{{Name: "A", StartToken: "<A>"}}).Demux()
+		Close(demux["A"])
+		if closed.Load() != 0 {
+			t.Fatalf("upstream closed before all outputs closed")
+		}
+		Close(demux[""])
+		if closed.Load() != 1 {
+			t.Fatalf("upstream closed %d times, want 1", closed.Load())
+		}
+	})
+
+	t.Run("fork closes upstream after all copies closed", func(t *testing.T) {
+		src, closed := trackClose(FromSlice([]int{1, 2, 3}))
+		ss := Fork(src, 2)
+		c, d := TeeReader(ss[1])
+		Close(ss[0])
+		Close(c)
+		Close(c) // 重复关闭不影响计数
+		if closed.Load() != 0 {
+			t.Fatalf("upstream closed before all copies closed")
+		}
+		expectStream(t, d, []int{1, 2, 3}, io.EOF)
+		Close(d)
+		if closed.Load() != 1 {
+			t.Fatalf("upstream closed %d times, want 1", closed.Load())
+		}
+		if _, err := d.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("substitute closes the original stream", func(t *testing.T) {
+		src, closed := trackClose(FromSlice([]string{"a", "b", "c"}))
+		s := SubstituteStream(src, func(v string) Stream[string] {
+			if v == "b" {
+				return FromSlice([]string{"x"})
+			}
+			return nil
+		})
+		expectStream(t, s, []string{"a", "x"}, io.EOF)
+		if closed.Load() != 1 {
+			t.Fatalf("original closed %d times, want 1", closed.Load())
+		}
+	})
+
+	t.Run("FromChan drains the channel", func(t *testing.T) {
+		ch := make(chan int)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer close(ch)
+			for i := range 100 {
+				ch <- i
+			}
+		}()
+		s := FromChan(ch)
+		s.Recv()
+		Close(s)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("producer goroutine leaked")
+		}
+	})
+
+	t.Run("WithBuffer stops reading", func(t *testing.T) {
+		src, closed := trackClose(blockingStream(1))
+		s := WithBuffer(src)
+		if v, err := s.Recv(); err != nil || v != 1 {
+			t.Fatalf("Recv() = %v, %v; want 1, nil", v, err)
+		}
+		Close(s)
+		if closed.Load() != 1 {
+			t.Fatalf("upstream closed %d times, want 1", closed.Load())
+		}
+		if _, err := s.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+
+	t.Run("FromFutureStream closed before ready", func(t *testing.T) {
+		ch := make(chan Stream[int], 1)
+		s := FromFutureStream(ch)
+		Close(s)
+		src, closed := trackClose(FromSlice([]int{1}))
+		ch <- src
+		deadline := time.Now().Add(time.Second)
+		for closed.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if closed.Load() != 1 {
+			t.Fatalf("future stream closed %d times, want 1", closed.Load())
+		}
+		if _, err := s.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+}
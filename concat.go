@@ -1,6 +1,9 @@
 package streams
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 type concatStream[T any] struct {
 	streams            []Stream[T]
@@ -10,13 +13,17 @@ type concatStream[T any] struct {
 }
 
 func (m *concatStream[T]) Recv() (T, error) {
+	return m.RecvContext(context.Background())
+}
+
+func (m *concatStream[T]) RecvContext(ctx context.Context) (T, error) {
 	for {
 		if m.index >= len(m.streams) {
 			var zero T
 			return zero, io.EOF
 		}
 		stream := m.streams[m.index]
-		value, err := stream.Recv()
+		value, err := RecvContext(ctx, stream)
 		if err == io.EOF {
 			if m.firstNonEmptyUsage && m.nonEmpty { // 首个非空流模式：第一个非空流已结束，整体可以结束了
 				var zero T
@@ -35,6 +42,11 @@ func (m *concatStream[T]) Recv() (T, error) {
 	}
 }
 
+// Close 关闭所有的流，包括还没开始读的流
+func (m *concatStream[T]) Close() error {
+	return closeAll(m.streams)
+}
+
 // Concat 将多个流合并为一个流
 func Concat[T any](streams ...Stream[T]) Stream[T] {
 	return &concatStream[T]{
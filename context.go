@@ -0,0 +1,67 @@
+package streams
+
+import (
+	"context"
+	"errors"
+)
+
+// ContextStream 是可被取消的流，RecvContext 需要在ctx取消时尽快返回ctx.Err()
+// 内置的算子都实现了该接口，并会把ctx透传给上游
+type ContextStream[T any] interface {
+	Stream[T]
+	RecvContext(ctx context.Context) (T, error)
+}
+
+// RecvContext 带context地从流中读取一个数据
+// 如果流实现了ContextStream则透传ctx，否则只在读取前检查一次ctx，此时阻塞中的Recv无法被打断
+func RecvContext[T any](ctx context.Context, stream Stream[T]) (T, error) {
+	if cs, ok := stream.(ContextStream[T]); ok {
+		return cs.RecvContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	return stream.Recv()
+}
+
+// isContextErr 判断err是否是由ctx取消导致的，这类错误只属于当前这次调用，不应该被缓存
+func isContextErr(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
+
+type contextStream[T any] struct {
+	src Stream[T]
+	ctx context.Context
+}
+
+// WithContext 将ctx绑定到流上，之后对该流的Recv都会在ctx取消时返回ctx.Err()
+// 典型用法是把请求的ctx绑定到流上，客户端断开时上游的阻塞读取会被及时打断
+func WithContext[T any](stream Stream[T], ctx context.Context) Stream[T] {
+	return &contextStream[T]{src: avoidNil(stream), ctx: ctx}
+}
+
+func (s *contextStream[T]) Recv() (T, error) {
+	return RecvContext(s.ctx, s.src)
+}
+
+func (s *contextStream[T]) Close() error {
+	return Close(s.src)
+}
+
+// RecvContext 同时受绑定的ctx和入参ctx控制
+func (s *contextStream[T]) RecvContext(ctx context.Context) (T, error) {
+	if ctx.Done() == nil {
+		return s.Recv()
+	}
+	merged, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	v, err := RecvContext(merged, s.src)
+	if isContextErr(merged, err) && s.ctx.Err() != nil && ctx.Err() == nil {
+		return v, s.ctx.Err()
+	}
+	return v, err
+}
@@ -0,0 +1,123 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingStream 返回一个先输出items、然后一直阻塞直到ctx取消的流
+func blockingStream[T any](items ...T) Stream[T] {
+	ch := make(chan T, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	return FromChan(ch)
+}
+
+func expectCanceledPromptly[T any](t *testing.T, s Stream[T]) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := RecvContext(ctx, s)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Fatalf("cancel took too long: %s", cost)
+	}
+}
+
+func TestRecvContext(t *testing.T) {
+	t.Run("FromChan", func(t *testing.T) {
+		expectCanceledPromptly(t, blockingStream[int]())
+	})
+
+	t.Run("FromFutureStream", func(t *testing.T) {
+		expectCanceledPromptly(t, FromFutureStream(make(chan Stream[int])))
+	})
+
+	t.Run("WithBuffer", func(t *testing.T) {
+		expectCanceledPromptly(t, WithBuffer(blockingStream[int]()))
+	})
+
+	t.Run("operators propagate", func(t *testing.T) {
+		ops := map[string]Stream[string]{
+			"Map":           Map(blockingStream[int](), func(i int) string { return "" }),
+			"Filter":        Filter(blockingStream[string](), func(string) bool { return true }),
+			"Concat":        Concat(FromSlice([]string{}), blockingStream[string]()),
+			"FirstNonEmpty": FirstNonEmpty(blockingStream[string]()),
+			"SkipN":         SkipN(blockingStream[string](), 1),
+			"TakeWhile":     TakeWhile(blockingStream[string](), func(string) bool { return true }),
+			"Substitute":    SubstituteStream(blockingStream[string](), nil),
+			"RemoveTokens":  NewRemoveTokensStream(blockingStream("<"), []string{"<x>"}),
+			"Label":         Map(NewLabelStream(blockingStream("<"), []SLabel{{Name: "x", StartToken: "<x>"}}), func(c LabeledChunk) string { return c.Chunk }),
+			"SpecialToken":  Map(NewSpecialTokenParserStream(blockingStream("<"), []string{"<x>"}), func(c LabeledChunk) string { return c.Chunk }),
+			"SplitReader":   NewStringReader(blockingStream("a")).ToLineReader(),
+			"ThrottleMerge": ThrottleMerge(blockingStream[string](), mergeStrings, time.Millisecond),
+			"ToSafe":        ToSafe(blockingStream[string]()),
+			"WithLog":       WithLog(blockingStream[string](), "key", func(string) {}),
+		}
+		for name, s := range ops {
+			t.Run(name, func(t *testing.T) {
+				expectCanceledPromptly(t, s)
+			})
+		}
+	})
+
+	t.Run("Fork", func(t *testing.T) {
+		a, b := TeeReader(blockingStream(1))
+		if v, err := a.Recv(); err != nil || v != 1 {
+			t.Fatalf("a.Recv() = %v, %v; want 1, nil", v, err)
+		}
+		expectCanceledPromptly(t, a)
+		// 被取消的读者不影响其他读者
+		if v, err := b.Recv(); err != nil || v != 1 {
+			t.Fatalf("b.Recv() = %v, %v; want 1, nil", v, err)
+		}
+		expectCanceledPromptly(t, b)
+	})
+
+	t.Run("cancel is not sticky", func(t *testing.T) {
+		ch := make(chan string, 1)
+		s := NewRemoveTokensStream(FromChan(ch), []string{"<x>"})
+		expectCanceledPromptly(t, s)
+		ch <- "hello<x>world"
+		close(ch)
+		expectStringStream(t, s, "helloworld", io.EOF)
+	})
+}
+
+func TestWithContext(t *testing.T) {
+	t.Run("bound ctx", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		s := WithContext(Map(blockingStream("a"), strings.ToUpper), ctx)
+		if v, err := s.Recv(); err != nil || v != "A" {
+			t.Fatalf("Recv() = %v, %v; want A, nil", v, err)
+		}
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if _, err := s.Recv(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected Canceled, got %v", err)
+		}
+	})
+
+	t.Run("both ctx", func(t *testing.T) {
+		s := WithContext(blockingStream[string](), context.Background())
+		expectCanceledPromptly(t, s)
+	})
+}
+
+func TestToChanContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ToChanContext(ctx, blockingStream(1, 2))
+	if v := <-ch; v != 1 {
+		t.Fatalf("got %d, want 1", v)
+	}
+	cancel()
+	for range ch { // 取消后channel会被关闭
+	}
+}
@@ -1,33 +1,85 @@
 package streams
 
 import (
+	"context"
 	"io"
+	"iter"
 	"sync"
 )
 
 type funcStream[T any] struct {
-	fn func() (T, error)
+	fn    func(ctx context.Context) (T, error)
+	close func() error
+}
+
+// newFuncStream 创建一个关闭时调用close的funcStream，内置算子用它把Close转发给上游
+func newFuncStream[T any](fn func(ctx context.Context) (T, error), close func() error) Stream[T] {
+	return &funcStream[T]{fn: fn, close: close}
+}
+
+// closerOf 返回关闭src的函数
+func closerOf[T any](src Stream[T]) func() error {
+	return func() error { return Close(src) }
 }
 
 func (f *funcStream[T]) Recv() (T, error) {
-	return f.fn()
+	return f.fn(context.Background())
+}
+
+func (f *funcStream[T]) RecvContext(ctx context.Context) (T, error) {
+	return f.fn(ctx)
+}
+
+func (f *funcStream[T]) Close() error {
+	if f.close == nil {
+		return nil
+	}
+	return f.close()
 }
 
 // FromFunc 通过一个Recv函数创建流
 func FromFunc[T any](fn func() (T, error)) Stream[T] {
+	return FromFuncContext(func(ctx context.Context) (T, error) {
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		return fn()
+	})
+}
+
+// FromFuncContext 通过一个感知context的Recv函数创建流，fn需要在ctx取消时尽快返回ctx.Err()
+func FromFuncContext[T any](fn func(ctx context.Context) (T, error)) Stream[T] {
 	return &funcStream[T]{fn: fn}
 }
 
 // FromChan 从chan中创建一个流
+// 注意事项：
+// 1. 生产方写完数据后必须close(ch)，FromChan没有办法通知生产方停止
+// 2. 流被关闭时，会起一个goroutine消费chan中剩余的数据直到ch被close，避免生产方阻塞在写入上；生产方一直不close(ch)的话，这个goroutine和生产方都会泄漏
 func FromChan[T any](ch <-chan T) Stream[T] {
-	return FromFunc(func() (T, error) {
-		v, ok := <-ch
-		if !ok {
-			var zero T
-			return zero, io.EOF
+	var once sync.Once
+	drain := func() error {
+		once.Do(func() {
+			go func() {
+				for range ch {
+				}
+			}()
+		})
+		return nil
+	}
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		var zero T
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return zero, io.EOF
+			}
+			return v, nil
+		case <-ctx.Done():
+			return zero, ctx.Err()
 		}
-		return v, nil
-	})
+	}, drain)
 }
 
 // FromSlice 从slice中创建一个流
@@ -43,6 +95,79 @@ func FromSlice[T any](slice []T) Stream[T] {
 	})
 }
 
+// FromSeq 从迭代器创建一个流，内部使用iter.Pull，首次Recv时才会开始迭代
+// 注意：没有消费完的流需要Close，否则iter.Pull的goroutine会泄漏
+func FromSeq[T any](seq iter.Seq[T]) Stream[T] {
+	return FromSeq2(func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	})
+}
+
+// FromSeq2 从带错误的迭代器创建一个流，迭代到非nil的错误时流以该错误结束(io.EOF视为正常结束)
+// 注意：没有消费完的流需要Close，否则iter.Pull的goroutine会泄漏
+func FromSeq2[T any](seq iter.Seq2[T, error]) Stream[T] {
+	var recvMu sync.Mutex // iter.Pull的next不能并发调用
+	var mu sync.Mutex     // 保护下面的状态，Close时不会被阻塞中的next卡住
+	var next func() (T, error, bool)
+	var stop func()
+	var done error
+	running := false // 是否有Recv正在调用next，是的话由它在next返回后调用stop
+
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		var zero T
+		recvMu.Lock()
+		defer recvMu.Unlock()
+
+		mu.Lock()
+		if done != nil {
+			mu.Unlock()
+			return zero, done
+		}
+		if err := ctx.Err(); err != nil {
+			mu.Unlock()
+			return zero, err
+		}
+		if next == nil {
+			next, stop = iter.Pull2(seq)
+		}
+		running = true
+		mu.Unlock()
+
+		v, err, ok := next()
+
+		mu.Lock()
+		defer mu.Unlock()
+		running = false
+		if done != nil { // next期间被Close了
+			stop()
+			return zero, done
+		}
+		if !ok {
+			err = io.EOF
+		}
+		if err != nil {
+			done = err
+			stop()
+			return zero, err
+		}
+		return v, nil
+	}, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if stop != nil && !running {
+			stop()
+		}
+		if done == nil {
+			done = ErrClosed
+		}
+		return nil
+	})
+}
+
 func FromErr[T any](err error) Stream[T] {
 	return FromFunc(func() (T, error) {
 		var zero T
@@ -52,16 +177,90 @@ func FromErr[T any](err error) Stream[T] {
 
 // FromFutureStream 接收一个Stream的chan，在未来消费时，才会从chan中取出这个Stream并消费。对于创建流耗时较长的场景十分有用。
 // 注意，只会从这个chan中取一个Stream。强烈建议chan用make([]Stream[T], 1)定义，避免生产方协程泄露。
+// 如果在取出Stream之前就被关闭，会起一个goroutine等待Stream创建好之后再关闭它
 func FromFutureStream[T any](ch <-chan Stream[T]) Stream[T] {
-	var mu sync.Mutex
+	var recvMu sync.Mutex // 保证同一时刻只有一个Recv
+	var mu sync.Mutex     // 保护下面的状态，Close时不会被阻塞中的Recv卡住
 	var src Stream[T]
+	waiting := false // 是否有Recv正在等待chan
+	closed := make(chan struct{})
 
-	return FromFunc(func() (T, error) {
+	closeLater := func() {
+		go func() {
+			if s, ok := <-ch; ok {
+				Close(avoidNil(s))
+			}
+		}()
+	}
+
+	closeSrc := func() error {
 		mu.Lock()
 		defer mu.Unlock()
-		if src == nil {
-			src = avoidNil(<-ch)
+		select {
+		case <-closed:
+			return nil
+		default:
 		}
-		return src.Recv()
-	})
+		close(closed)
+		if src != nil {
+			return Close(src)
+		}
+		if !waiting { // 正在等待的Recv会负责关闭取出的Stream
+			closeLater()
+		}
+		return nil
+	}
+
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		var zero T
+		recvMu.Lock()
+		defer recvMu.Unlock()
+
+		mu.Lock()
+		s := src
+		if s == nil {
+			select {
+			case <-closed:
+				mu.Unlock()
+				return zero, ErrClosed
+			default:
+			}
+			waiting = true
+		}
+		mu.Unlock()
+
+		if s == nil {
+			select {
+			case s = <-ch:
+				s = avoidNil(s)
+			case <-closed:
+				s = nil
+			case <-ctx.Done():
+			}
+
+			mu.Lock()
+			waiting = false
+			isClosed := false
+			select {
+			case <-closed:
+				isClosed = true
+			default:
+			}
+			if isClosed {
+				mu.Unlock()
+				if s != nil {
+					Close(s)
+				} else {
+					closeLater()
+				}
+				return zero, ErrClosed
+			}
+			src = s
+			mu.Unlock()
+			if s == nil {
+				return zero, ctx.Err()
+			}
+		}
+		return RecvContext(ctx, s)
+	}, closeSrc)
 }
@@ -0,0 +1,83 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestFromSeq(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		expectStream(t, FromSeq(slices.Values([]int{1, 2, 3})), []int{1, 2, 3}, io.EOF)
+	})
+
+	t.Run("close stops the pull goroutine", func(t *testing.T) {
+		stopped := false
+		s := FromSeq(iter.Seq[int](func(yield func(int) bool) {
+			defer func() { stopped = true }()
+			for i := 0; ; i++ {
+				if !yield(i) {
+					return
+				}
+			}
+		}))
+		expectStream(t, TakeWhile(s, func(i int) bool { return i < 3 }), []int{0, 1, 2}, io.EOF)
+		Close(s)
+		if !stopped {
+			t.Fatal("seq was not stopped")
+		}
+		if _, err := s.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+}
+
+func TestFromSeq2(t *testing.T) {
+	boom := errors.New("boom")
+	seq := func(yield func(string, error) bool) {
+		if !yield("a", nil) {
+			return
+		}
+		if !yield("", boom) {
+			return
+		}
+		yield("unreachable", nil)
+	}
+	s := FromSeq2(seq)
+	expectStream(t, s, []string{"a"}, boom)
+	if _, err := s.Recv(); err != boom {
+		t.Fatalf("expected sticky error, got %v", err)
+	}
+
+	t.Run("close while next is running", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		stopped := make(chan struct{})
+		s := FromSeq2(iter.Seq2[int, error](func(yield func(int, error) bool) {
+			defer close(stopped)
+			close(started)
+			<-release
+			for i := 0; ; i++ {
+				if !yield(i, nil) {
+					return
+				}
+			}
+		}))
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := s.Recv()
+			errCh <- err
+		}()
+		<-started
+		Close(s)
+		close(release)
+		if err := <-errCh; !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+		<-stopped
+	})
+
+	// 与Iter2互为逆操作
+	expectStream(t, FromSeq2(Iter2(FromSlice([]int{1, 2}))), []int{1, 2}, io.EOF)
+}
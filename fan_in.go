@@ -0,0 +1,132 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Indexed 带来源下标的数据，Index是该数据所属的流在入参中的下标
+type Indexed[T any] struct {
+	Index int
+	Value T
+}
+
+// MergeErrorPolicy 合并的某个流出错时的处理策略
+type MergeErrorPolicy int
+
+const (
+	MergeFailFast MergeErrorPolicy = iota // 任一流出错时立即返回该错误，并关闭其他流
+	MergeContinue                         // 出错的流被移除，其他流继续，全部结束后返回所有错误
+)
+
+type mergeEvent[T any] struct {
+	Indexed[T]
+	err error
+}
+
+type mergeStream[T any] struct {
+	streams []Stream[T]
+	policy  MergeErrorPolicy
+
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	events    chan mergeEvent[T]
+	running   int
+	errs      []error
+	err       error // 结束之后Recv都返回这个错误
+}
+
+// Merge 并发读取多个流，谁先读到数据就先输出谁，所有流都结束时结束，任一流出错时立即返回该错误
+// 和Concat不同，Merge会为每个流起一个goroutine，所以没有消费完的流需要Close
+// 需要知道数据来源或者需要其他错误策略时使用MergeIndexed
+func Merge[T any](streams ...Stream[T]) Stream[T] {
+	return Map(MergeIndexed(MergeFailFast, streams...), func(v Indexed[T]) T { return v.Value })
+}
+
+// MergeIndexed 同Merge，输出的数据带有来源流的下标，policy决定某个流出错时的处理方式
+// MergeContinue策略下，所有流结束后，如果有流出错，返回errors.Join后的错误，否则返回io.EOF
+func MergeIndexed[T any](policy MergeErrorPolicy, streams ...Stream[T]) Stream[Indexed[T]] {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &mergeStream[T]{
+		streams: avoidNils(streams),
+		policy:  policy,
+		ctx:     ctx,
+		cancel:  cancel,
+		events:  make(chan mergeEvent[T]),
+		running: len(streams),
+	}
+}
+
+func (m *mergeStream[T]) start() {
+	for i, s := range m.streams {
+		go func() {
+			for {
+				v, err := RecvContext(m.ctx, s)
+				if isContextErr(m.ctx, err) {
+					return
+				}
+				select {
+				case m.events <- mergeEvent[T]{Indexed: Indexed[T]{Index: i, Value: v}, err: err}:
+				case <-m.ctx.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (m *mergeStream[T]) Recv() (Indexed[T], error) {
+	return m.RecvContext(context.Background())
+}
+
+func (m *mergeStream[T]) RecvContext(ctx context.Context) (Indexed[T], error) {
+	var zero Indexed[T]
+	for {
+		if m.err != nil {
+			return zero, m.err
+		}
+		if m.running == 0 {
+			m.err = io.EOF
+			if len(m.errs) > 0 {
+				m.err = errors.Join(m.errs...)
+			}
+			continue
+		}
+		m.startOnce.Do(m.start)
+
+		var ev mergeEvent[T]
+		select {
+		case ev = <-m.events:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-m.ctx.Done():
+			return zero, ErrClosed
+		}
+		if ev.err == nil {
+			return ev.Indexed, nil
+		}
+		m.running--
+		if ev.err == io.EOF {
+			continue
+		}
+		if m.policy == MergeFailFast {
+			m.err = ev.err
+			m.Close()
+			return zero, ev.err
+		}
+		m.errs = append(m.errs, fmt.Errorf("merged stream %d: %w", ev.Index, ev.err))
+	}
+}
+
+// Close 停止所有goroutine并关闭所有流
+func (m *mergeStream[T]) Close() error {
+	m.cancel()
+	return closeAll(m.streams)
+}
@@ -0,0 +1,101 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// delayedStream 每个数据之前等待一段时间
+func delayedStream[T any](delay time.Duration, items ...T) Stream[T] {
+	src := FromSlice(items)
+	return FromFunc(func() (T, error) {
+		time.Sleep(delay)
+		return src.Recv()
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("interleaves by arrival", func(t *testing.T) {
+		slow, fast := make(chan string, 1), make(chan string, 1)
+		s := Merge(FromChan(slow), FromChan(fast))
+		// 每次只让一个源产生数据，输出顺序完全由到达顺序决定
+		for _, step := range []struct {
+			ch chan string
+			v  string
+		}{{fast, "fast1"}, {slow, "slow1"}, {fast, "fast2"}, {slow, "slow2"}} {
+			step.ch <- step.v
+			if v, err := s.Recv(); v != step.v || err != nil {
+				t.Fatalf("Recv() = %v, %v; want %v, nil", v, err, step.v)
+			}
+		}
+		close(slow)
+		close(fast)
+		expectStream(t, s, []string{}, io.EOF)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		expectStream(t, Merge[int](), []int{}, io.EOF)
+		expectStream(t, Merge(Empty[int](), nil), []int{}, io.EOF)
+	})
+
+	t.Run("indexed", func(t *testing.T) {
+		s := MergeIndexed(MergeFailFast, FromSlice([]string{"a"}), FromSlice([]string{"b", "c"}))
+		var got []Indexed[string]
+		for v := range Iter(s) {
+			got = append(got, v)
+		}
+		slices.SortStableFunc(got, func(a, b Indexed[string]) int { return a.Index - b.Index })
+		want := []Indexed[string]{{0, "a"}, {1, "b"}, {1, "c"}}
+		if !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		boom := errors.New("boom")
+		other, closed := trackClose(blockingStream[string]())
+		s := Merge(Concat(delayedStream(10*time.Millisecond, "a"), FromErr[string](boom)), other)
+		expectStream(t, s, []string{"a"}, boom)
+		if closed.Load() != 1 {
+			t.Fatal("other streams were not closed on error")
+		}
+	})
+
+	t.Run("continue on error", func(t *testing.T) {
+		boom := errors.New("boom")
+		s := MergeIndexed(MergeContinue, FromErr[string](boom), delayedStream(10*time.Millisecond, "a", "b"))
+		var got []string
+		var err error
+		for {
+			var v Indexed[string]
+			v, err = s.Recv()
+			if err != nil {
+				break
+			}
+			got = append(got, v.Value)
+		}
+		if !slices.Equal(got, []string{"a", "b"}) || !errors.Is(err, boom) {
+			t.Fatalf("got %v, %v; want [a b], boom", got, err)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		a, closedA := trackClose(blockingStream("a"))
+		b, closedB := trackClose(blockingStream[string]())
+		s := Merge(a, b)
+		if v, err := s.Recv(); v != "a" || err != nil {
+			t.Fatalf("Recv() = %v, %v; want a, nil", v, err)
+		}
+		expectCanceledPromptly(t, s)
+		Close(s)
+		if closedA.Load() != 1 || closedB.Load() != 1 {
+			t.Fatal("merged streams were not closed")
+		}
+		if _, err := s.Recv(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+}
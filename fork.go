@@ -1,104 +1,297 @@
 package streams
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrLagged 读者落后太多被踢出，见LagError
+var ErrLagged = errors.New("streams: fork reader lagged too far behind")
+
+// LagPolicy 副本之间的差距超过WithMaxLag设置的上限时的处理策略
+type LagPolicy int
+
+const (
+	LagBlock LagPolicy = iota // 快的读者等待慢的读者追上来
+	LagDrop                   // 踢出最慢的读者，其后续Recv返回io.EOF
+	LagError                  // 踢出最慢的读者，其后续Recv返回ErrLagged
+)
+
+type forkConfig struct {
+	maxLag    int
+	lagPolicy LagPolicy
+}
+
+type ForkOption func(*forkConfig)
+
+// WithMaxLag 限制最快的读者最多领先最慢的读者maxLag个数据，即缓存最多保留maxLag个数据，超过时按policy处理
+// 注意：LagBlock策略下，如果多个副本是在同一个协程中先后消费的，会导致死锁
+func WithMaxLag(maxLag int, policy LagPolicy) ForkOption {
+	return func(c *forkConfig) {
+		c.maxLag = maxLag
+		c.lagPolicy = policy
+	}
+}
 
 type forkStream[T any] struct {
 	cache *streamCache[T]
-	index int
+	index int   // 下一个要读取的数据的绝对下标，受cache.mu保护
+	err   error // 被关闭或踢出之后Recv返回的错误，受cache.mu保护
 }
 
 type streamCache[T any] struct {
-	src Stream[T]
-	buf []T
-	err error
-	mu  sync.RWMutex
+	src     Stream[T]
+	cfg     forkConfig
+	buf     []T
+	base    int // buf[0]的绝对下标，所有读者都读过的前缀会被丢弃
+	err     error
+	readers map[*forkStream[T]]struct{} // 还未关闭的读者，全部关闭时关闭上游并释放缓存
+	loading bool                        // 是否已有协程在读取上游
+	keepAll bool                        // 保留全部历史数据，并且读者全部关闭时不关闭上游，用于Broadcaster
+	notify  chan struct{}               // 读取结束、前缀被丢弃或读者离开时关闭并替换，用于唤醒等待的协程
+	mu      sync.Mutex
 }
 
-func newCache[T any](src Stream[T]) *streamCache[T] {
-	return &streamCache[T]{
-		src: avoidNil(src),
+func newCache[T any](src Stream[T], opts ...ForkOption) *streamCache[T] {
+	c := &streamCache[T]{
+		src:     avoidNil(src),
+		readers: make(map[*forkStream[T]]struct{}),
+		notify:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&c.cfg)
 	}
+	return c
 }
 
-func (c *streamCache[T]) tryGet(i int) (bool, T, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// attach 新增一个从index开始读取的读者，调用方需持有锁
+func (c *streamCache[T]) attach(index int) *forkStream[T] {
+	t := &forkStream[T]{cache: c, index: index}
+	if c.readers == nil { // 缓存已经释放
+		t.err = ErrClosed
+		return t
+	}
+	c.readers[t] = struct{}{}
+	return t
+}
 
-	// 已有足够数据或已结束
-	if i < len(c.buf) {
-		return true, c.buf[i], nil
+// detach 移除一个读者，之后它的Recv都返回err。返回是否所有读者都已离开，调用方需持有锁
+func (c *streamCache[T]) detach(t *forkStream[T], err error) bool {
+	if _, ok := c.readers[t]; !ok {
+		return false
 	}
-	var zero T
-	if c.err != nil {
-		return true, zero, c.err
+	delete(c.readers, t)
+	t.err = err
+	if len(c.readers) > 0 || c.keepAll {
+		c.trim()
+		c.wakeUp()
+		return false
 	}
-	return false, zero, nil
+	c.readers = nil
+	c.buf = nil
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.wakeUp()
+	return true
 }
 
-func (c *streamCache[T]) blockLoad(i int) (T, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *streamCache[T]) wakeUp() {
+	close(c.notify)
+	c.notify = make(chan struct{})
+}
 
-	// 如果等待锁的期间，其他协程已经拉到数据了
-	if i < len(c.buf) {
-		return c.buf[i], nil
+// minIndex 返回最慢读者的下标，调用方需持有锁
+func (c *streamCache[T]) minIndex() int {
+	slowest := c.base + len(c.buf)
+	for t := range c.readers {
+		if t.index < slowest {
+			slowest = t.index
+		}
+	}
+	return slowest
+}
+
+// trim 丢弃所有读者都已经读过的前缀，返回是否有数据被丢弃，调用方需持有锁
+func (c *streamCache[T]) trim() bool {
+	if c.keepAll {
+		return false
+	}
+	n := c.minIndex() - c.base
+	if n <= 0 {
+		return false
+	}
+	clear(c.buf[:n]) // 让丢弃的数据可以被GC
+	c.buf = c.buf[n:]
+	c.base += n
+	return true
+}
+
+// dropSlowest 按策略踢出最慢的读者，调用方需持有锁
+func (c *streamCache[T]) dropSlowest() {
+	err := io.EOF
+	if c.cfg.lagPolicy == LagError {
+		err = ErrLagged
+	}
+	slowest := c.minIndex()
+	for t := range c.readers {
+		if t.index == slowest {
+			c.detach(t, err)
+		}
 	}
-	if c.err != nil {
-		var zero T
-		return zero, c.err
+}
+
+// Get 读取t的下一个数据，同一时刻只有一个协程读取上游，其他协程等待读取结果
+// 读取上游时不持有锁，所以已经缓存的数据不会被慢上游阻塞
+func (c *streamCache[T]) Get(ctx context.Context, t *forkStream[T]) (T, error) {
+	var zero T
+	c.mu.Lock()
+	for {
+		if t.err != nil {
+			err := t.err
+			c.mu.Unlock()
+			return zero, err
+		}
+		// 已有足够数据或已结束
+		if i := t.index - c.base; i < len(c.buf) {
+			v := c.buf[i]
+			t.index++
+			if c.trim() {
+				c.wakeUp()
+			}
+			c.mu.Unlock()
+			return v, nil
+		}
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return zero, err
+		}
+		if c.cfg.maxLag > 0 && t.index-c.minIndex() >= c.cfg.maxLag { // 再读一个就超过上限了
+			if c.cfg.lagPolicy != LagBlock {
+				c.dropSlowest()
+				continue
+			}
+		} else if !c.loading {
+			break
+		}
+		// 其他协程正在读取，或者需要等慢的读者追上来，等状态变化后再检查一次
+		notify := c.notify
+		c.mu.Unlock()
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+		c.mu.Lock()
 	}
+	// 到这里说明当前协程是第一个需要新数据的，那我们读一条并缓存
+	c.loading = true
+	c.mu.Unlock()
 
-	// 到这里说明当前协程是第一个拿到锁的，那我们读一条并缓存
-	val, err := c.src.Recv()
+	val, err := RecvContext(ctx, c.src)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading = false
+	if c.readers == nil { // 读取期间所有读者都关闭了
+		c.wakeUp()
+		return zero, ErrClosed
+	}
 	if err == nil {
 		c.buf = append(c.buf, val)
-	} else {
+		t.index++
+		c.trim()
+	} else if !isContextErr(ctx, err) { // ctx取消只影响当前读者，不缓存
 		c.err = err
 	}
+	c.wakeUp()
 	return val, err
 }
 
-func (c *streamCache[T]) Get(i int) (T, error) {
-	if ok, val, err := c.tryGet(i); ok {
-		return val, err
+// Close 关闭读者，所有读者都关闭之后关闭上游
+func (c *streamCache[T]) Close(t *forkStream[T]) error {
+	c.mu.Lock()
+	allClosed := c.detach(t, ErrClosed)
+	c.mu.Unlock()
+	if allClosed {
+		return Close(c.src)
 	}
-	return c.blockLoad(i)
+	return nil
+}
+
+// shutdown 关闭所有读者并关闭上游
+func (c *streamCache[T]) shutdown() error {
+	c.mu.Lock()
+	if c.readers == nil {
+		c.mu.Unlock()
+		return nil
+	}
+	for t := range c.readers {
+		t.err = ErrClosed
+	}
+	c.readers = nil
+	c.buf = nil
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.wakeUp()
+	c.mu.Unlock()
+	return Close(c.src)
 }
 
 func (t *forkStream[T]) Recv() (T, error) {
-	index := t.index
-	t.index++
-	return t.cache.Get(index)
+	return t.RecvContext(context.Background())
+}
+
+func (t *forkStream[T]) RecvContext(ctx context.Context) (T, error) {
+	return t.cache.Get(ctx, t)
+}
+
+// Close 关闭当前副本，所有副本都关闭之后才会关闭上游
+func (t *forkStream[T]) Close() error {
+	return t.cache.Close(t)
 }
 
 // TeeReader 将一条流复制为两条流，需要注意入参的流不可再消费
-func TeeReader[T any](src Stream[T]) (Stream[T], Stream[T]) {
-	ss := Fork(src, 2)
+func TeeReader[T any](src Stream[T], opts ...ForkOption) (Stream[T], Stream[T]) {
+	ss := Fork(src, 2, opts...)
 	return ss[0], ss[1]
 }
 
 // Fork 将一条流复制为多条流，需要注意入参的流不可再消费
-func Fork[T any](src Stream[T], copies int) []Stream[T] {
+// 缓存只保留还有副本没读过的数据，每个副本都需要Close或者消费完毕，所有副本都关闭之后才会关闭上游
+func Fork[T any](src Stream[T], copies int, opts ...ForkOption) []Stream[T] {
 	src = avoidNil(src)
 	res := make([]Stream[T], copies)
-	if unwrapped, ok := src.(*forkStream[T]); ok && unwrapped != nil { // 性能优化：如果入参是forkStream，则直接复用缓存
+	if unwrapped, ok := src.(*forkStream[T]); ok && unwrapped != nil && len(opts) == 0 { // 性能优化：如果入参是forkStream，则直接复用缓存
 		cache := unwrapped.cache
+		cache.mu.Lock()
 		for i := range res {
-			res[i] = &forkStream[T]{cache: cache, index: unwrapped.index}
+			if unwrapped.err != nil { // 入参已经被关闭或踢出
+				res[i] = FromErr[T](unwrapped.err)
+			} else {
+				res[i] = cache.attach(unwrapped.index)
+			}
 		}
+		cache.mu.Unlock()
+		// 入参的位置由新副本接管，入参视为已关闭
+		unwrapped.Close()
 	} else {
-		cache := newCache(src)
+		cache := newCache(src, opts...)
 		for i := range res {
-			res[i] = &forkStream[T]{cache: cache, index: 0}
+			res[i] = cache.attach(0)
 		}
 	}
 
 	return res
 }
 
-func Demux[T any](src Stream[T], classifier func(T) string, labelsRange []string) map[string]Stream[T] {
+func Demux[T any](src Stream[T], classifier func(T) string, labelsRange []string, opts ...ForkOption) map[string]Stream[T] {
 	demuxRes := make(map[string]Stream[T], len(labelsRange)+1)
-	copyStreams := Fork(src, len(labelsRange)+1)
+	copyStreams := Fork(src, len(labelsRange)+1, opts...)
 	for i, label := range labelsRange {
 		demuxRes[label] = Filter(copyStreams[i], func(t T) bool {
 			return classifier(t) == label
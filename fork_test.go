@@ -5,6 +5,7 @@ import (
 	"io"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestTeeReader(t *testing.T) {
@@ -90,3 +91,74 @@ func TestTeeReader(t *testing.T) {
 		}
 	})
 }
+
+func TestForkBoundedCache(t *testing.T) {
+	t.Run("consumed prefix is discarded", func(t *testing.T) {
+		a, b := TeeReader(FromSlice([]int{1, 2, 3, 4, 5}))
+		cache := a.(*forkStream[int]).cache
+		for want := 1; want <= 5; want++ {
+			if v, _ := a.Recv(); v != want {
+				t.Fatalf("a.Recv() = %d, want %d", v, want)
+			}
+			if v, _ := b.Recv(); v != want {
+				t.Fatalf("b.Recv() = %d, want %d", v, want)
+			}
+			if len(cache.buf) != 0 {
+				t.Fatalf("cache holds %d items after both readers passed", len(cache.buf))
+			}
+		}
+		expectStream(t, a, []int{}, io.EOF)
+		expectStream(t, b, []int{}, io.EOF)
+	})
+
+	t.Run("closed reader does not pin the cache", func(t *testing.T) {
+		a, b := TeeReader(FromSlice([]int{1, 2, 3}))
+		Close(b)
+		expectStream(t, a, []int{1, 2, 3}, io.EOF)
+		if n := len(a.(*forkStream[int]).cache.buf); n != 0 {
+			t.Fatalf("cache holds %d items", n)
+		}
+	})
+
+	t.Run("block policy", func(t *testing.T) {
+		a, b := TeeReader(FromSlice([]int{1, 2, 3, 4}), WithMaxLag(2, LagBlock))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			expectStream(t, a, []int{1, 2, 3, 4}, io.EOF)
+		}()
+		select {
+		case <-done:
+			t.Fatal("fast reader should be blocked by the slow reader")
+		case <-time.After(20 * time.Millisecond):
+		}
+		cache := a.(*forkStream[int]).cache
+		cache.mu.Lock()
+		n := len(cache.buf)
+		cache.mu.Unlock()
+		if n != 2 {
+			t.Fatalf("cache holds %d items, want 2", n)
+		}
+		expectStream(t, b, []int{1, 2, 3, 4}, io.EOF)
+		<-done
+	})
+
+	t.Run("drop policy", func(t *testing.T) {
+		a, b := TeeReader(FromSlice([]int{1, 2, 3, 4}), WithMaxLag(2, LagDrop))
+		b.Recv()
+		expectStream(t, a, []int{1, 2, 3, 4}, io.EOF)
+		expectStream(t, b, []int{}, io.EOF)
+	})
+
+	t.Run("error policy", func(t *testing.T) {
+		a, b := TeeReader(FromSlice([]int{1, 2, 3, 4}), WithMaxLag(2, LagError))
+		expectStream(t, a, []int{1, 2, 3, 4}, io.EOF)
+		expectStream(t, b, []int{}, ErrLagged)
+	})
+
+	t.Run("context cancels blocked reader", func(t *testing.T) {
+		a, _ := TeeReader(FromSlice([]int{1, 2, 3}), WithMaxLag(1, LagBlock))
+		a.Recv()
+		expectCanceledPromptly(t, a)
+	})
+}
@@ -1,5 +1,7 @@
 package streams
 
+import "context"
+
 type transformedStream[T any, R any] struct {
 	Stream[T]
 	mapper func(T, error) (R, error)
@@ -10,6 +12,14 @@ func (h *transformedStream[T, R]) Recv() (R, error) {
 	return h.mapper(h.Stream.Recv())
 }
 
+func (h *transformedStream[T, R]) RecvContext(ctx context.Context) (R, error) {
+	return h.mapper(RecvContext(ctx, h.Stream))
+}
+
+func (h *transformedStream[T, R]) Close() error {
+	return Close(h.Stream)
+}
+
 // Map 将流中的元素映射为另一个类型
 func Map[T any, R any](src Stream[T], mapper func(T) R) Stream[R] {
 	return MapErr(src, func(t T, err error) (R, error) {
@@ -35,8 +45,12 @@ type filterStream[T any] struct {
 }
 
 func (f *filterStream[T]) Recv() (T, error) {
+	return f.RecvContext(context.Background())
+}
+
+func (f *filterStream[T]) RecvContext(ctx context.Context) (T, error) {
 	for {
-		v, err := f.Stream.Recv()
+		v, err := RecvContext(ctx, f.Stream)
 		if err != nil {
 			var zero T
 			return zero, err
@@ -47,6 +61,10 @@ func (f *filterStream[T]) Recv() (T, error) {
 	}
 }
 
+func (f *filterStream[T]) Close() error {
+	return Close(f.Stream)
+}
+
 func Filter[T any](src Stream[T], validate func(T) bool) Stream[T] {
 	return &filterStream[T]{
 		Stream:   avoidNil(src),
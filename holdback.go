@@ -0,0 +1,119 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ParserOption 文本解析器(NewLabelStream、NewSpecialTokenParserStream、NewRemoveTokensStream、NewTagParser、NewStringReader)的可选配置
+type ParserOption func(*parserOptions)
+
+type parserOptions struct {
+	maxHoldback time.Duration
+
+	nestedLabels   bool // 只对NewLabelStream生效
+	labelEndPolicy LabelEndPolicy
+}
+
+// WithMaxHoldback 文本因为可能是某个token的前缀而被暂存超过d时，不再等待上游，直接当作普通文本输出
+// 对StringReader.ReadUntil来说，超过d还没遇到delim时，直接返回已经读到的数据
+// 注意：超时后上游的Recv会在后台继续等待，结果留给下一次读取，所以上游即使不支持RecvContext也不会丢数据；后台的Recv在解析器Close时取消
+func WithMaxHoldback(d time.Duration) ParserOption {
+	return func(o *parserOptions) {
+		o.maxHoldback = d
+	}
+}
+
+func newParserOptions(opts []ParserOption) parserOptions {
+	var o parserOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// errHoldbackExpired 暂存的文本超时了，调用方需要把暂存的文本输出
+var errHoldbackExpired = errors.New("streams: holdback expired")
+
+type recvResult struct {
+	val string
+	err error
+}
+
+// holdbackReceiver 读取上游的chunk，有暂存的文本时最多等待maxHoldback
+type holdbackReceiver struct {
+	src         Stream[string]
+	maxHoldback time.Duration
+
+	heldSince time.Time       // 开始暂存文本的时间
+	pending   chan recvResult // 超时后还在后台进行的Recv
+	ctx       context.Context // 后台Recv使用的ctx，不受某一次调用的ctx影响，close时取消
+	cancel    context.CancelFunc
+}
+
+func newHoldbackReceiver(src Stream[string], opts []ParserOption) holdbackReceiver {
+	ctx, cancel := context.WithCancel(context.Background())
+	return holdbackReceiver{
+		src:         avoidNil(src),
+		maxHoldback: newParserOptions(opts).maxHoldback,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// close 取消后台的Recv并关闭上游
+func (h *holdbackReceiver) close() error {
+	h.cancel()
+	return Close(h.src)
+}
+
+// recv 读取上游的下一个chunk，held表示调用方当前是否暂存着文本，暂存超时返回errHoldbackExpired
+func (h *holdbackReceiver) recv(ctx context.Context, held bool) (string, error) {
+	if !held {
+		h.heldSince = time.Time{}
+	} else if h.heldSince.IsZero() {
+		h.heldSince = time.Now()
+	}
+	var deadline time.Time
+	if held && h.maxHoldback > 0 {
+		deadline = h.heldSince.Add(h.maxHoldback)
+	}
+	return h.recvUntil(ctx, deadline)
+}
+
+// recvUntil 读取上游的下一个chunk，deadline不为零时最多等到deadline，超时返回errHoldbackExpired
+func (h *holdbackReceiver) recvUntil(ctx context.Context, deadline time.Time) (string, error) {
+	if h.pending == nil && deadline.IsZero() {
+		return RecvContext(ctx, h.src)
+	}
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return "", errHoldbackExpired
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	if h.pending == nil {
+		pending := make(chan recvResult, 1)
+		h.pending = pending
+		go func() {
+			v, err := RecvContext(h.ctx, h.src)
+			pending <- recvResult{val: v, err: err}
+		}()
+	}
+
+	select {
+	case r := <-h.pending:
+		h.pending = nil
+		return r.val, r.err
+	case <-timeout:
+		return "", errHoldbackExpired
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,107 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+// slowStream 先输出first，间隔delay之后再输出rest
+func slowStream(first string, delay time.Duration, rest ...string) Stream[string] {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		ch <- first
+		time.Sleep(delay)
+		for _, v := range rest {
+			ch <- v
+		}
+	}()
+	return FromChan(ch)
+}
+
+func TestWithMaxHoldback(t *testing.T) {
+	t.Run("RemoveTokens flushes held prefix", func(t *testing.T) {
+		s := NewRemoveTokensStream(slowStream("hi<|e", 100*time.Millisecond, "nd|>!"), []string{"<|end|>"}, WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, s, []string{"hi", "<|e", "nd|>!"}, io.EOF)
+	})
+
+	t.Run("RemoveTokens without timeout", func(t *testing.T) {
+		s := NewRemoveTokensStream(slowStream("hi<|e", 20*time.Millisecond, "nd|>!"), []string{"<|end|>"})
+		expectStream(t, s, []string{"hi", "!"}, io.EOF)
+	})
+
+	t.Run("token completes before deadline", func(t *testing.T) {
+		s := NewRemoveTokensStream(slowStream("hi<|e", 5*time.Millisecond, "nd|>!"), []string{"<|end|>"}, WithMaxHoldback(time.Second))
+		expectStream(t, s, []string{"hi", "!"}, io.EOF)
+	})
+
+	t.Run("SpecialToken", func(t *testing.T) {
+		s := NewSpecialTokenParserStream(slowStream("a<", 100*time.Millisecond, "x>b"), []string{"<x>"}, WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, s, []LabeledChunk{{Chunk: "a"}, {Chunk: "<"}, {Chunk: "x>b"}}, io.EOF)
+	})
+
+	t.Run("Label", func(t *testing.T) {
+		s := NewLabelStream(slowStream("<A>a</", 100*time.Millisecond, "A>b"), []SLabel{{Name: "A", StartToken: "<A>", EndToken: "</A>"}}, WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, s, []LabeledChunk{{Label: "A", Chunk: "a"}, {Label: "A", Chunk: "</"}, {Label: "A", Chunk: "A>b"}}, io.EOF)
+	})
+
+	t.Run("ReadUntil", func(t *testing.T) {
+		sr := NewStringReader(slowStream("line", 100*time.Millisecond, "1\nline2"), WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, sr.ToLineReader(), []string{"line", "1\n", "line2"}, io.EOF)
+	})
+
+	t.Run("close cancels background recv", func(t *testing.T) {
+		canceled := make(chan struct{})
+		first := true
+		src := FromFuncContext(func(ctx context.Context) (string, error) {
+			if first {
+				first = false
+				return "a<|e", nil
+			}
+			<-ctx.Done()
+			close(canceled)
+			return "", ctx.Err()
+		})
+		s := NewRemoveTokensStream(src, []string{"<|end|>"}, WithMaxHoldback(10*time.Millisecond))
+		for _, want := range []string{"a", "<|e"} {
+			if v, err := s.Recv(); v != want || err != nil {
+				t.Fatalf("Recv() = %q, %v; want %q", v, err, want)
+			}
+		}
+		Close(s)
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("background recv was not canceled")
+		}
+	})
+
+	t.Run("Typewriter close cancels background recv", func(t *testing.T) {
+		canceled := make(chan struct{})
+		first := true
+		src := FromFuncContext(func(ctx context.Context) (string, error) {
+			if first {
+				first = false
+				return "ab", nil
+			}
+			<-ctx.Done()
+			close(canceled)
+			return "", ctx.Err()
+		})
+		s := Typewriter(src, 10, WithTypewriterInterval(time.Millisecond))
+		if v, err := s.Recv(); v != "a" || err != nil {
+			t.Fatalf("Recv() = %q, %v", v, err)
+		}
+		if v, err := s.Recv(); v != "b" || err != nil {
+			t.Fatalf("Recv() = %q, %v", v, err)
+		}
+		Close(s)
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("background recv was not canceled")
+		}
+	})
+}
@@ -0,0 +1,457 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON 流中的文本不是合法的JSON
+var ErrInvalidJSON = errors.New("streams: invalid json")
+
+type JSONEventKind int
+
+const (
+	JSONObjectStart JSONEventKind = iota
+	JSONObjectEnd
+	JSONArrayStart
+	JSONArrayEnd
+	JSONKey         // 对象的key，Path是该成员的路径
+	JSONStringDelta // 字符串值新增的部分，字符串结束时还会输出一个JSONValue
+	JSONValue       // 完整的标量值
+)
+
+type JSONEvent struct {
+	Kind  JSONEventKind
+	Path  string // 事件所在值的JSONPath，比如$.steps[0].text
+	Key   string // JSONKey时是key
+	Delta string // JSONStringDelta时是字符串新增的部分(已解码)
+	Value any    // JSONValue时是完整的值：string、float64、bool或nil
+}
+
+// jsonSeg 路径中的一段，index<0时表示对象的key
+type jsonSeg struct {
+	key   string
+	index int
+}
+
+func formatJSONPath(segs []jsonSeg) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, seg := range segs {
+		if seg.index >= 0 {
+			sb.WriteString("[" + strconv.Itoa(seg.index) + "]")
+		} else if isJSONPathIdent(seg.key) {
+			sb.WriteString("." + seg.key)
+		} else {
+			sb.WriteString("[" + strconv.Quote(seg.key) + "]")
+		}
+	}
+	return sb.String()
+}
+
+func isJSONPathIdent(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonToken 解析器内部的事件，segs是事件发生时路径的拷贝
+type jsonToken struct {
+	kind  JSONEventKind
+	segs  []jsonSeg
+	key   string
+	delta string
+	value any
+}
+
+func (t jsonToken) event() JSONEvent {
+	return JSONEvent{Kind: t.kind, Path: formatJSONPath(t.segs), Key: t.key, Delta: t.delta, Value: t.value}
+}
+
+type jsonState int
+
+const (
+	jsonExpectValue jsonState = iota
+	jsonExpectKey
+	jsonExpectColon
+	jsonAfterValue
+	jsonInString
+	jsonInNumber
+	jsonInLiteral
+)
+
+// jsonParser 增量的JSON词法和语法分析器，每次write一个chunk，解析出的事件追加到tokens中
+type jsonParser struct {
+	state   jsonState
+	arrays  []bool // 从外到内的容器，true表示数组
+	segs    []jsonSeg
+	emptyOK bool // 刚进入容器，可以直接结束
+
+	str       strings.Builder // 正在解析的字符串(已解码)
+	strKey    bool
+	deltaFrom int    // str中还没有作为delta输出的起始位置
+	esc       []byte // 未完成的转义序列，以'\\'开头
+	surrogate rune   // 等待低位代理的高位代理
+	lit       []byte // 正在解析的数字或字面量
+	offset    int    // 已经处理的字节数，用于错误信息
+
+	tokens []jsonToken
+}
+
+func (p *jsonParser) emit(t jsonToken) {
+	t.segs = append([]jsonSeg(nil), p.segs...)
+	p.tokens = append(p.tokens, t)
+}
+
+func (p *jsonParser) syntaxErr(c byte) error {
+	return fmt.Errorf("%w: unexpected %q at offset %d", ErrInvalidJSON, c, p.offset)
+}
+
+func (p *jsonParser) write(chunk string) error {
+	for i := 0; i < len(chunk); {
+		if p.state == jsonInString && len(p.esc) == 0 && p.surrogate == 0 {
+			// 快速路径：直接拷贝到下一个引号或者转义符
+			j := strings.IndexAny(chunk[i:], "\"\\")
+			if j < 0 {
+				j = len(chunk) - i
+			}
+			p.str.WriteString(chunk[i : i+j])
+			i += j
+			p.offset += j
+			if i == len(chunk) {
+				break
+			}
+		}
+		if err := p.step(chunk[i]); err != nil {
+			return err
+		}
+		i++
+		p.offset++
+	}
+	p.flushDelta(false)
+	return nil
+}
+
+// finish 上游结束时调用，结束正在解析的数字或字面量，并检查JSON是否完整
+func (p *jsonParser) finish() error {
+	if p.state == jsonInNumber || p.state == jsonInLiteral {
+		if err := p.finishScalar(); err != nil {
+			return err
+		}
+	}
+	if len(p.arrays) > 0 || p.state != jsonAfterValue && p.state != jsonExpectValue {
+		return fmt.Errorf("%w: unexpected end of input", ErrInvalidJSON)
+	}
+	return nil
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func (p *jsonParser) step(c byte) error {
+	switch p.state {
+	case jsonInString:
+		return p.stringByte(c)
+	case jsonInNumber, jsonInLiteral:
+		if p.state == jsonInNumber && strings.IndexByte("+-.eE0123456789", c) >= 0 ||
+			p.state == jsonInLiteral && c >= 'a' && c <= 'z' {
+			p.lit = append(p.lit, c)
+			return nil
+		}
+		if err := p.finishScalar(); err != nil {
+			return err
+		}
+		return p.step(c)
+	}
+
+	if isJSONSpace(c) {
+		return nil
+	}
+	inArray := len(p.arrays) > 0 && p.arrays[len(p.arrays)-1]
+	switch p.state {
+	case jsonExpectValue:
+		if c == ']' && p.emptyOK && inArray {
+			return p.closeContainer()
+		}
+		return p.beginValue(c)
+	case jsonExpectKey:
+		if c == '}' && p.emptyOK {
+			return p.closeContainer()
+		}
+		if c != '"' {
+			return p.syntaxErr(c)
+		}
+		p.startString(true)
+	case jsonExpectColon:
+		if c != ':' {
+			return p.syntaxErr(c)
+		}
+		p.state = jsonExpectValue
+	case jsonAfterValue:
+		switch {
+		case len(p.arrays) == 0: // 下一个顶层值
+			return p.beginValue(c)
+		case c == ',' && inArray:
+			p.segs[len(p.segs)-1].index++
+			p.state = jsonExpectValue
+		case c == ',':
+			p.state = jsonExpectKey
+		case c == ']' && inArray, c == '}' && !inArray:
+			return p.closeContainer()
+		default:
+			return p.syntaxErr(c)
+		}
+	}
+	return nil
+}
+
+func (p *jsonParser) beginValue(c byte) error {
+	p.emptyOK = false
+	switch {
+	case c == '{':
+		p.emit(jsonToken{kind: JSONObjectStart})
+		p.arrays = append(p.arrays, false)
+		p.segs = append(p.segs, jsonSeg{index: -1})
+		p.state = jsonExpectKey
+		p.emptyOK = true
+	case c == '[':
+		p.emit(jsonToken{kind: JSONArrayStart})
+		p.arrays = append(p.arrays, true)
+		p.segs = append(p.segs, jsonSeg{index: 0})
+		p.state = jsonExpectValue
+		p.emptyOK = true
+	case c == '"':
+		p.startString(false)
+	case c == '-' || c >= '0' && c <= '9':
+		p.state = jsonInNumber
+		p.lit = append(p.lit[:0], c)
+	case c >= 'a' && c <= 'z':
+		p.state = jsonInLiteral
+		p.lit = append(p.lit[:0], c)
+	default:
+		return p.syntaxErr(c)
+	}
+	return nil
+}
+
+func (p *jsonParser) closeContainer() error {
+	array := p.arrays[len(p.arrays)-1]
+	p.arrays = p.arrays[:len(p.arrays)-1]
+	p.segs = p.segs[:len(p.segs)-1]
+	p.emptyOK = false
+	p.state = jsonAfterValue
+	if array {
+		p.emit(jsonToken{kind: JSONArrayEnd})
+	} else {
+		p.emit(jsonToken{kind: JSONObjectEnd})
+	}
+	return nil
+}
+
+func (p *jsonParser) finishScalar() error {
+	var v any
+	if err := json.Unmarshal(p.lit, &v); err != nil {
+		return fmt.Errorf("%w: invalid literal %q at offset %d", ErrInvalidJSON, p.lit, p.offset-len(p.lit))
+	}
+	p.emit(jsonToken{kind: JSONValue, value: v})
+	p.state = jsonAfterValue
+	return nil
+}
+
+func (p *jsonParser) startString(key bool) {
+	p.state = jsonInString
+	p.strKey = key
+	p.str.Reset()
+	p.deltaFrom = 0
+}
+
+func (p *jsonParser) stringByte(c byte) error {
+	if len(p.esc) == 0 {
+		if p.surrogate != 0 && c != '\\' { // 高位代理后面没有低位代理
+			p.str.WriteRune(utf8.RuneError)
+			p.surrogate = 0
+		}
+		switch c {
+		case '"':
+			return p.finishString()
+		case '\\':
+			p.esc = append(p.esc, c)
+		default:
+			p.str.WriteByte(c) // 未转义的控制字符也原样接受
+		}
+		return nil
+	}
+
+	p.esc = append(p.esc, c)
+	if len(p.esc) == 2 {
+		var r byte
+		switch c {
+		case '"', '\\', '/':
+			r = c
+		case 'b':
+			r = '\b'
+		case 'f':
+			r = '\f'
+		case 'n':
+			r = '\n'
+		case 'r':
+			r = '\r'
+		case 't':
+			r = '\t'
+		case 'u':
+			return nil
+		default:
+			return p.syntaxErr(c)
+		}
+		if p.surrogate != 0 {
+			p.str.WriteRune(utf8.RuneError)
+			p.surrogate = 0
+		}
+		p.str.WriteByte(r)
+		p.esc = p.esc[:0]
+		return nil
+	}
+	if len(p.esc) < 6 {
+		return nil
+	}
+	n, err := strconv.ParseUint(string(p.esc[2:]), 16, 16)
+	if err != nil {
+		return fmt.Errorf("%w: invalid escape %q at offset %d", ErrInvalidJSON, p.esc, p.offset-5)
+	}
+	p.esc = p.esc[:0]
+	r := rune(n)
+	if p.surrogate != 0 {
+		dec := utf16.DecodeRune(p.surrogate, r)
+		p.surrogate = 0
+		if dec != utf8.RuneError {
+			p.str.WriteRune(dec)
+			return nil
+		}
+		p.str.WriteRune(utf8.RuneError)
+	}
+	if utf16.IsSurrogate(r) && r < 0xdc00 { // 高位代理，等待后面的低位代理
+		p.surrogate = r
+		return nil
+	}
+	p.str.WriteRune(r)
+	return nil
+}
+
+func (p *jsonParser) finishString() error {
+	s := p.str.String()
+	if p.strKey {
+		p.segs[len(p.segs)-1].key = s
+		p.emit(jsonToken{kind: JSONKey, key: s})
+		p.state = jsonExpectColon
+		return nil
+	}
+	p.flushDelta(true)
+	p.emit(jsonToken{kind: JSONValue, value: s})
+	p.state = jsonAfterValue
+	return nil
+}
+
+// flushDelta 输出字符串值新增的部分，字符串还没结束时，末尾不完整的utf8字符留到下次
+func (p *jsonParser) flushDelta(final bool) {
+	if p.state != jsonInString || p.strKey {
+		return
+	}
+	s := p.str.String()
+	end := len(s)
+	if !final {
+		end = completeUTF8Len(s)
+	}
+	if end > p.deltaFrom {
+		p.emit(jsonToken{kind: JSONStringDelta, delta: s[p.deltaFrom:end]})
+		p.deltaFrom = end
+	}
+}
+
+// completeUTF8Len 去掉末尾不完整的utf8字符之后的长度
+func completeUTF8Len(s string) int {
+	for i := len(s) - 1; i >= 0 && i >= len(s)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			if !utf8.FullRuneInString(s[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(s)
+}
+
+type jsonParserStream struct {
+	src    Stream[string]
+	parser jsonParser
+	eof    bool
+	err    error
+}
+
+// NewJSONParser 增量解析流中的JSON文本，输出对象、数组的开始和结束、key、字符串增量和完整的标量值
+// 注意事项：
+// 1. 支持多个顶层值连续出现(比如JSON Lines)，每个顶层值的路径都是$
+// 2. 字符串值每次收到新的数据都会输出一次JSONStringDelta，字符串结束时再输出完整的JSONValue
+// 3. 数字和字面量在遇到分隔符时才算完整，数字统一解析为float64
+// 4. 遇到不合法的JSON时返回包装了ErrInvalidJSON的错误，上游结束时JSON不完整也会返回该错误
+func NewJSONParser(src Stream[string]) Stream[JSONEvent] {
+	return Map[jsonToken](newJSONParserStream(src), jsonToken.event)
+}
+
+func newJSONParserStream(src Stream[string]) *jsonParserStream {
+	return &jsonParserStream{src: avoidNil(src)}
+}
+
+func (s *jsonParserStream) Recv() (jsonToken, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *jsonParserStream) RecvContext(ctx context.Context) (jsonToken, error) {
+	p := &s.parser
+	for {
+		if len(p.tokens) > 0 {
+			t := p.tokens[0]
+			p.tokens = p.tokens[1:]
+			return t, nil
+		}
+		if s.err != nil {
+			return jsonToken{}, s.err
+		}
+		if s.eof {
+			s.err = p.finish()
+			if s.err == nil {
+				s.err = io.EOF
+			}
+			continue
+		}
+
+		chunk, err := RecvContext(ctx, s.src)
+		if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return jsonToken{}, err
+		}
+		if err := p.write(chunk); err != nil {
+			s.err = err
+		}
+	}
+}
+
+func (s *jsonParserStream) Close() error {
+	return Close(s.src)
+}
@@ -0,0 +1,97 @@
+package streams
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 用jsonBuilder把事件还原成值，逐字符输入和整体输入都要和json.Unmarshal的结果一致
+func TestJSONParser(t *testing.T) {
+	inputs := []string{
+		`{"a":1,"b":[true,false,null,-1.5e2],"c":{"d":"x\"y\\né😀"},"e":[],"f":{}}`,
+		`  [ "中文", {"k" : [ [ ] ] } ]  `,
+		`"plain"`,
+		`12`,
+	}
+	for _, input := range inputs {
+		var want any
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatal(err)
+		}
+		for _, chunks := range [][]string{strings.Split(input, ""), {input}} {
+			s := newJSONParserStream(FromSlice(chunks))
+			var b jsonBuilder
+			var got any
+			var deltas strings.Builder
+			for {
+				tok, err := s.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: %v", input, err)
+				}
+				if tok.kind == JSONStringDelta {
+					deltas.WriteString(tok.delta)
+				}
+				if b.feed(tok) {
+					got = b.root
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %#v, want %#v", got, want)
+			}
+		}
+	}
+
+	t.Run("events", func(t *testing.T) {
+		src := FromSlice([]string{`{"steps":[{"te`, `xt":"he`, `llo"}]}`})
+		want := []JSONEvent{
+			{Kind: JSONObjectStart, Path: "$"},
+			{Kind: JSONKey, Path: "$.steps", Key: "steps"},
+			{Kind: JSONArrayStart, Path: "$.steps"},
+			{Kind: JSONObjectStart, Path: "$.steps[0]"},
+			{Kind: JSONKey, Path: "$.steps[0].text", Key: "text"},
+			{Kind: JSONStringDelta, Path: "$.steps[0].text", Delta: "he"},
+			{Kind: JSONStringDelta, Path: "$.steps[0].text", Delta: "llo"},
+			{Kind: JSONValue, Path: "$.steps[0].text", Value: "hello"},
+			{Kind: JSONObjectEnd, Path: "$.steps[0]"},
+			{Kind: JSONArrayEnd, Path: "$.steps"},
+			{Kind: JSONObjectEnd, Path: "$"},
+		}
+		expectStream(t, NewJSONParser(src), want, io.EOF)
+	})
+
+	t.Run("split utf8 in delta", func(t *testing.T) {
+		src := FromSlice([]string{`"a` + "\xe4\xb8", "\xad" + `b"`})
+		want := []JSONEvent{
+			{Kind: JSONStringDelta, Path: "$", Delta: "a"},
+			{Kind: JSONStringDelta, Path: "$", Delta: "中b"},
+			{Kind: JSONValue, Path: "$", Value: "a中b"},
+		}
+		expectStream(t, NewJSONParser(src), want, io.EOF)
+	})
+
+	t.Run("path quoting", func(t *testing.T) {
+		src := FromSlice([]string{`{"a.b":1}`})
+		expectStream(t, NewJSONParser(src), []JSONEvent{
+			{Kind: JSONObjectStart, Path: "$"},
+			{Kind: JSONKey, Path: `$["a.b"]`, Key: "a.b"},
+			{Kind: JSONValue, Path: `$["a.b"]`, Value: float64(1)},
+			{Kind: JSONObjectEnd, Path: "$"},
+		}, io.EOF)
+	})
+
+	for _, input := range []string{`{"a" 1}`, `[1,]`, `{"a":tru}`, `{"a":1`, `"abc`} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			err := Consume(NewJSONParser(FromSlice([]string{input})), func(JSONEvent) error { return nil })
+			if !errors.Is(err, ErrInvalidJSON) {
+				t.Fatalf("expected ErrInvalidJSON, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSeg JSONPath表达式中的一段
+type jsonPathSeg struct {
+	key      string
+	index    int // <0时表示对象的key
+	wildcard bool
+}
+
+// parseJSONPath 解析JSONPath表达式，支持$、.key、['key']、["key"]、[n]、[*]、.*
+func parseJSONPath(path string) ([]jsonPathSeg, error) {
+	invalid := func() ([]jsonPathSeg, error) {
+		return nil, fmt.Errorf("streams: invalid json path %q", path)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return invalid()
+	}
+	var segs []jsonPathSeg
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".*"):
+			segs = append(segs, jsonPathSeg{index: -1, wildcard: true})
+			rest = rest[2:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : 1+end]
+			if key == "" {
+				return invalid()
+			}
+			segs = append(segs, jsonPathSeg{key: key, index: -1})
+			rest = rest[1+end:]
+		case strings.HasPrefix(rest, "[*]"):
+			segs = append(segs, jsonPathSeg{index: -1, wildcard: true})
+			rest = rest[3:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest[2:], "']")
+			if end < 0 {
+				return invalid()
+			}
+			segs = append(segs, jsonPathSeg{key: rest[2 : 2+end], index: -1})
+			rest = rest[2+end+2:]
+		case strings.HasPrefix(rest, `["`):
+			quoted, err := strconv.QuotedPrefix(rest[1:])
+			if err != nil || !strings.HasPrefix(rest[1+len(quoted):], "]") {
+				return invalid()
+			}
+			key, _ := strconv.Unquote(quoted)
+			segs = append(segs, jsonPathSeg{key: key, index: -1})
+			rest = rest[1+len(quoted)+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return invalid()
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return invalid()
+			}
+			segs = append(segs, jsonPathSeg{index: index})
+			rest = rest[end+1:]
+		default:
+			return invalid()
+		}
+	}
+	return segs, nil
+}
+
+func matchJSONPath(pattern []jsonPathSeg, segs []jsonSeg) bool {
+	if len(pattern) != len(segs) {
+		return false
+	}
+	for i, p := range pattern {
+		seg := segs[i]
+		switch {
+		case p.wildcard:
+		case p.index >= 0:
+			if seg.index != p.index {
+				return false
+			}
+		default:
+			if seg.index >= 0 || seg.key != p.key {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type jsonFrame struct {
+	array  bool
+	arr    []any
+	obj    map[string]any
+	key    string
+	hasKey bool // 已经收到key，还没有收到值
+}
+
+func (f *jsonFrame) value() any {
+	if f.array {
+		return f.arr
+	}
+	return f.obj
+}
+
+// jsonBuilder 根据jsonToken还原出完整的值，map[string]any、[]any和标量，和json.Unmarshal到any的结果一致
+type jsonBuilder struct {
+	stack   []*jsonFrame
+	root    any
+	hasRoot bool
+	partial strings.Builder // 还没有结束的字符串值
+	inStr   bool
+}
+
+// feed 输入一个jsonToken，返回true表示一个完整的值已经还原到root中
+func (b *jsonBuilder) feed(t jsonToken) bool {
+	switch t.kind {
+	case JSONObjectStart:
+		b.stack = append(b.stack, &jsonFrame{obj: make(map[string]any)})
+	case JSONArrayStart:
+		b.stack = append(b.stack, &jsonFrame{array: true, arr: []any{}})
+	case JSONObjectEnd, JSONArrayEnd:
+		f := b.stack[len(b.stack)-1]
+		b.stack = b.stack[:len(b.stack)-1]
+		return b.add(f.value())
+	case JSONKey:
+		f := b.stack[len(b.stack)-1]
+		f.key, f.hasKey = t.key, true
+	case JSONStringDelta:
+		b.partial.WriteString(t.delta)
+		b.inStr = true
+	case JSONValue:
+		b.partial.Reset()
+		b.inStr = false
+		return b.add(t.value)
+	}
+	return false
+}
+
+func (b *jsonBuilder) add(v any) bool {
+	if len(b.stack) == 0 {
+		b.root, b.hasRoot = v, true
+		return true
+	}
+	f := b.stack[len(b.stack)-1]
+	if f.array {
+		f.arr = append(f.arr, v)
+	} else {
+		f.obj[f.key] = v
+		f.hasKey = false
+	}
+	return false
+}
+
+// JSONMatch JSONPath订阅到的值
+type JSONMatch struct {
+	Path  string // 匹配到的具体路径，比如$.steps[0].text
+	Delta string // 字符串值新增的部分，Done为false时有值
+	Value any    // 完整的值，Done为true时有值
+	Done  bool
+}
+
+type jsonPathStream struct {
+	src     *jsonParserStream
+	pattern []jsonPathSeg
+
+	builder *jsonBuilder // 正在还原的对象或数组
+	path    string
+}
+
+// JSONPath 增量解析流中的JSON，输出路径匹配path的值，比如$.steps[*].text
+// 注意事项：
+// 1. 字符串值每次收到新的数据都会输出一个Delta，结束时再输出一个Done的完整值
+// 2. 对象和数组在结束时作为完整的值输出一次，值的类型和json.Unmarshal到any一致
+// 3. path支持$、.key、['key']、["key"]、[n]、[*]、.*，不支持..和过滤表达式
+func JSONPath(src Stream[string], path string) (Stream[JSONMatch], error) {
+	pattern, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonPathStream{src: newJSONParserStream(src), pattern: pattern}, nil
+}
+
+func (s *jsonPathStream) Recv() (JSONMatch, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *jsonPathStream) RecvContext(ctx context.Context) (JSONMatch, error) {
+	for {
+		t, err := s.src.RecvContext(ctx)
+		if err != nil {
+			return JSONMatch{}, err
+		}
+		if s.builder != nil {
+			if s.builder.feed(t) {
+				v := s.builder.root
+				s.builder = nil
+				return JSONMatch{Path: s.path, Value: v, Done: true}, nil
+			}
+			continue
+		}
+		if !matchJSONPath(s.pattern, t.segs) {
+			continue
+		}
+		switch t.kind {
+		case JSONObjectStart, JSONArrayStart:
+			s.builder = &jsonBuilder{}
+			s.builder.feed(t)
+			s.path = formatJSONPath(t.segs)
+		case JSONStringDelta:
+			return JSONMatch{Path: formatJSONPath(t.segs), Delta: t.delta}, nil
+		case JSONValue:
+			return JSONMatch{Path: formatJSONPath(t.segs), Value: t.value, Done: true}, nil
+		}
+	}
+}
+
+func (s *jsonPathStream) Close() error {
+	return s.src.Close()
+}
@@ -0,0 +1,69 @@
+package streams
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	input := []string{`{"steps":[{"text":"he`, `llo","n":1},{"text":"wor`, `ld","n":2}],"meta":{"a":[1,2]}}`}
+
+	t.Run("string deltas", func(t *testing.T) {
+		s, err := JSONPath(FromSlice(input), "$.steps[*].text")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectStream(t, s, []JSONMatch{
+			{Path: "$.steps[0].text", Delta: "he"},
+			{Path: "$.steps[0].text", Delta: "llo"},
+			{Path: "$.steps[0].text", Value: "hello", Done: true},
+			{Path: "$.steps[1].text", Delta: "wor"},
+			{Path: "$.steps[1].text", Delta: "ld"},
+			{Path: "$.steps[1].text", Value: "world", Done: true},
+		}, io.EOF)
+	})
+
+	t.Run("composite values", func(t *testing.T) {
+		for path, want := range map[string]JSONMatch{
+			"$.meta":        {Path: "$.meta", Value: map[string]any{"a": []any{float64(1), float64(2)}}, Done: true},
+			"$['meta'].a":   {Path: "$.meta.a", Value: []any{float64(1), float64(2)}, Done: true},
+			`$["steps"][1]`: {Path: "$.steps[1]", Value: map[string]any{"text": "world", "n": float64(2)}, Done: true},
+		} {
+			s, err := JSONPath(FromSlice(input), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []JSONMatch
+			Consume(s, func(m JSONMatch) error {
+				got = append(got, m)
+				return nil
+			})
+			if !reflect.DeepEqual(got, []JSONMatch{want}) {
+				t.Errorf("%s: got %#v, want %#v", path, got, want)
+			}
+		}
+	})
+
+	t.Run("wildcard key", func(t *testing.T) {
+		s, _ := JSONPath(FromSlice(input), "$.steps[*].*")
+		var n int
+		Consume(s, func(m JSONMatch) error {
+			if m.Done {
+				n++
+			}
+			return nil
+		})
+		if n != 4 {
+			t.Fatalf("got %d values, want 4", n)
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		for _, path := range []string{"steps", "$.", "$[x]", "$..a", "$['a"} {
+			if _, err := JSONPath(FromSlice(input), path); err == nil {
+				t.Errorf("%s: expected error", path)
+			}
+		}
+	})
+}
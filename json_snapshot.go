@@ -0,0 +1,137 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// snapshot 把已经收到的部分修复成一个完整的值：闭合没有结束的字符串、数组和对象，忽略还没有收到值的key和不完整的数字、字面量
+// 返回的值和内部状态不共享可变的容器，ok为false表示还没有任何值
+func (b *jsonBuilder) snapshot() (v any, ok bool) {
+	if b.inStr {
+		v, ok = b.partial.String(), true
+	}
+	for i := len(b.stack) - 1; i >= 0; i-- {
+		f := b.stack[i]
+		if f.array {
+			arr := deepCopyJSON(f.arr).([]any)
+			if ok {
+				arr = append(arr, v)
+			}
+			v = arr
+		} else {
+			obj := deepCopyJSON(f.obj).(map[string]any)
+			if ok && f.hasKey {
+				obj[f.key] = v
+			}
+			v = obj
+		}
+		ok = true
+	}
+	if !ok {
+		return deepCopyJSON(b.root), b.hasRoot
+	}
+	return v, true
+}
+
+// deepCopyJSON 深拷贝jsonBuilder还原出来的值，已经结束的子对象和子数组也要拷贝，否则会和之后的快照共享
+func deepCopyJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(v))
+		for k, child := range v {
+			obj[k] = deepCopyJSON(child)
+		}
+		return obj
+	case []any:
+		arr := make([]any, len(v), len(v)+1) // 多留一个位置给没有结束的元素
+		for i, child := range v {
+			arr[i] = deepCopyJSON(child)
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+type JSONSnapshotOption func(*jsonSnapshotOptions)
+
+type jsonSnapshotOptions struct {
+	throttle time.Duration
+}
+
+// WithSnapshotThrottle 按ThrottleMerge的语义先把上游的chunk攒起来再解析，首个chunk立即解析，之后最多每隔d输出一次快照
+func WithSnapshotThrottle(d time.Duration) JSONSnapshotOption {
+	return func(o *jsonSnapshotOptions) {
+		o.throttle = d
+	}
+}
+
+// JSONSnapshots 增量解析流中的JSON片段，每收到一个chunk就输出一次修复后的完整值，用于实时预览
+// 注意事项：
+// 1. 没有结束的字符串、数组和对象会被闭合，还没有收到值的key、不完整的数字和字面量会被忽略
+// 2. 快照的类型和json.Unmarshal到any一致，每次输出的快照互不共享可变的容器，下游可以随意修改
+// 3. 没有产生新内容的chunk不会输出快照；JSON不合法或者上游结束时JSON不完整，返回包装了ErrInvalidJSON的错误
+// 4. 每个快照都需要深拷贝一遍已经收到的内容，文档较大时建议使用WithSnapshotThrottle
+func JSONSnapshots(src Stream[string], opts ...JSONSnapshotOption) Stream[any] {
+	var o jsonSnapshotOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	src = avoidNil(src)
+	if o.throttle > 0 {
+		src = ThrottleMerge(src, func(a, b string) (string, bool) { return a + b, true }, o.throttle)
+	}
+
+	var parser jsonParser
+	var builder jsonBuilder
+	var err error
+	return newFuncStream(func(ctx context.Context) (any, error) {
+		for err == nil {
+			chunk, recvErr := RecvContext(ctx, src)
+			if isContextErr(ctx, recvErr) {
+				return nil, recvErr
+			}
+			if recvErr == io.EOF {
+				if err = parser.finish(); err == nil {
+					err = io.EOF
+				}
+			} else if recvErr != nil {
+				err = recvErr
+				break
+			} else {
+				err = parser.write(chunk)
+			}
+
+			changed := len(parser.tokens) > 0
+			for _, t := range parser.tokens {
+				builder.feed(t)
+			}
+			parser.tokens = parser.tokens[:0]
+			if changed {
+				if v, ok := builder.snapshot(); ok {
+					return v, nil
+				}
+			}
+		}
+		return nil, err
+	}, closerOf(src))
+}
+
+// JSONSnapshotsOf 同JSONSnapshots，但是把每个快照转换成T，字段缺失时保持零值
+func JSONSnapshotsOf[T any](src Stream[string], opts ...JSONSnapshotOption) Stream[T] {
+	return MapErr(JSONSnapshots(src, opts...), func(v any, err error) (T, error) {
+		var t T
+		if err != nil {
+			return t, err
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return t, err
+		}
+		err = json.Unmarshal(data, &t)
+		return t, err
+	})
+}
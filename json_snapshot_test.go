@@ -0,0 +1,92 @@
+package streams
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONSnapshots(t *testing.T) {
+	t.Run("repair", func(t *testing.T) {
+		src := FromSlice([]string{`{"a":"he`, `llo","b":[1,`, `2`, `,{"c":tr`, `ue}],"d":`, `null}`})
+		var got []string
+		err := Consume(JSONSnapshots(src), func(v any) error {
+			data, _ := json.Marshal(v)
+			got = append(got, string(data))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			`{"a":"he"}`,
+			`{"a":"hello","b":[1]}`, // 数字遇到分隔符才完整，所以"2"这个chunk不输出快照
+			`{"a":"hello","b":[1,2,{}]}`,
+			`{"a":"hello","b":[1,2,{"c":true}]}`,
+			`{"a":"hello","b":[1,2,{"c":true}],"d":null}`,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("snapshots are independent", func(t *testing.T) {
+		s := JSONSnapshots(FromSlice([]string{`{"a":[1`, `,2]}`}))
+		first, _ := s.Recv()
+		first.(map[string]any)["a"] = nil
+		second, _ := s.Recv()
+		if want := map[string]any{"a": []any{float64(1), float64(2)}}; !reflect.DeepEqual(second, want) {
+			t.Fatalf("got %v, want %v", second, want)
+		}
+	})
+
+	t.Run("finished children are not shared", func(t *testing.T) {
+		s := JSONSnapshots(FromSlice([]string{`{"a":{"b":1},"c":"x`, `y`, `z"}`}))
+		first, _ := s.Recv()
+		first.(map[string]any)["a"].(map[string]any)["b"] = 99
+		second, _ := s.Recv()
+		if want := map[string]any{"a": map[string]any{"b": float64(1)}, "c": "xy"}; !reflect.DeepEqual(second, want) {
+			t.Fatalf("got %v, want %v", second, want)
+		}
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		type plan struct {
+			Title string   `json:"title"`
+			Steps []string `json:"steps"`
+		}
+		src := FromSlice([]string{`{"title":"T","steps":["a`, `","b"]}`})
+		expectStream(t, Map(JSONSnapshotsOf[plan](src), func(p plan) string { return p.Title + ":" + strings.Join(p.Steps, ",") }), []string{"T:a", "T:a,b"}, io.EOF)
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for _, c := range []string{`[`, `1`, `,`, `2`, `,`, `3]`} {
+				ch <- c
+			}
+		}()
+		var n int
+		var last any
+		Consume(JSONSnapshots(FromChan(ch), WithSnapshotThrottle(50*time.Millisecond)), func(v any) error {
+			n++
+			last = v
+			return nil
+		})
+		if n > 3 || !reflect.DeepEqual(last, []any{float64(1), float64(2), float64(3)}) {
+			t.Fatalf("got %d snapshots, last %v", n, last)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		err := Consume(JSONSnapshots(FromSlice([]string{`{"a":`})), func(any) error { return nil })
+		if !errors.Is(err, ErrInvalidJSON) {
+			t.Fatalf("expected ErrInvalidJSON, got %v", err)
+		}
+	})
+}
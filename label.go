@@ -1,11 +1,12 @@
 package streams
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"slices"
 	"strings"
-	"unicode/utf8"
 )
 
 type SLabel struct {
@@ -14,124 +15,279 @@ type SLabel struct {
 	EndToken   string
 }
 
+// LabelEndPolicy 嵌套模式下，遇到的endToken不属于最内层label时的处理策略
+type LabelEndPolicy int
+
+const (
+	LabelEndAsText    LabelEndPolicy = iota // 当作普通文本输出
+	LabelEndAutoClose                       // 如果是外层某个label的endToken，则关闭该label以及它内部的所有label，否则当作普通文本
+	LabelEndError                           // 返回ErrLabelMismatch
+)
+
+// ErrLabelMismatch 嵌套模式下遇到了不属于最内层label的endToken
+var ErrLabelMismatch = errors.New("streams: mismatched label end token")
+
+// WithNestedLabels 让NewLabelStream进入嵌套模式：label可以匹配任意多次，也可以互相嵌套，LabeledChunk.Path是从外到内用"/"连接的label名
+// 注意事项：
+// 1. startToken和endToken都不会输出
+// 2. 最先出现的token优先，多个token从同一位置开始时，最内层label的endToken优先，其次是越前面的label的startToken
+// 3. startToken为空的label不能嵌套，只有第一个会在流开始时进入，endToken为空的label会一直持续到流结束(或者被外层label自动关闭)
+// 4. policy决定遇到不属于最内层label的endToken时怎么处理，包括没有任何label时遇到的endToken
+func WithNestedLabels(policy LabelEndPolicy) ParserOption {
+	return func(o *parserOptions) {
+		o.nestedLabels = true
+		o.labelEndPolicy = policy
+	}
+}
+
+type labelAction int
+
+const (
+	labelOpen     labelAction = iota // 进入label
+	labelClose                       // 关闭stack[depth:]
+	labelMismatch                    // 不匹配的endToken
+)
+
+// labelToken 嵌套模式下，scanner中每个token对应的动作
+type labelToken struct {
+	action labelAction
+	label  int // labelOpen时是labels的下标，labelClose时是关闭之后stack的深度
+}
+
 type labelStream struct {
-	src    Stream[string]
-	labels []SLabel
+	src    holdbackReceiver
+	labels []SLabel // 还没有使用过的label，嵌套模式下是全部label
+	names  []string
 
-	buffer       string
-	minBufferLen int
+	scanner      tokenScanner
 	currentLabel *SLabel
+	eof          bool
+
+	// 嵌套模式
+	nested    bool
+	endPolicy LabelEndPolicy
+	stack     []*SLabel // 从外到内已经进入的label
+	path      string
+	actions   []labelToken
+	started   bool
+	err       error
 }
 
-func NewLabelStream(src Stream[string], labels []SLabel) *labelStream {
+func NewLabelStream(src Stream[string], labels []SLabel, opts ...ParserOption) *labelStream {
 	if err := checkLabels(labels); err != nil {
 		panic(err) // labels是写在代码里的，所以panic相对安全，会在开发阶段暴露
 	}
 
-	minBufferLen := 0
+	names := make([]string, 0, len(labels))
 	for _, lb := range labels {
-		if utf8.RuneCountInString(lb.StartToken) > minBufferLen {
-			minBufferLen = len(lb.StartToken)
+		names = append(names, lb.Name)
+	}
+	o := newParserOptions(opts)
+	s := &labelStream{
+		src:       newHoldbackReceiver(src, opts),
+		labels:    slices.Clone(labels),
+		names:     names,
+		nested:    o.nestedLabels,
+		endPolicy: o.labelEndPolicy,
+	}
+	if s.nested {
+		s.scanner = newTokenScanner(s.nestedTokens())
+	} else {
+		s.scanner = newTokenScanner(s.tokens())
+	}
+	return s
+}
+
+// nestedTokens 嵌套模式下需要匹配的token，按优先级排列：最内层label的endToken、所有label的startToken、其他endToken
+func (s *labelStream) nestedTokens() []string {
+	tokens := make([]string, 0, 2*len(s.labels)+1)
+	s.actions = s.actions[:0]
+	add := func(token string, t labelToken) {
+		tokens = append(tokens, token)
+		s.actions = append(s.actions, t)
+	}
+	if n := len(s.stack); n > 0 {
+		add(s.stack[n-1].EndToken, labelToken{action: labelClose, label: n - 1})
+	}
+	for i, lb := range s.labels {
+		add(lb.StartToken, labelToken{action: labelOpen, label: i})
+	}
+	switch s.endPolicy {
+	case LabelEndAutoClose:
+		for i := len(s.stack) - 2; i >= 0; i-- {
+			add(s.stack[i].EndToken, labelToken{action: labelClose, label: i})
 		}
-		if utf8.RuneCountInString(lb.EndToken) > minBufferLen {
-			minBufferLen = len(lb.EndToken)
+	case LabelEndError:
+		for _, lb := range s.labels {
+			add(lb.EndToken, labelToken{action: labelMismatch})
 		}
 	}
+	return tokens
+}
 
-	return &labelStream{
-		src:          avoidNil(src),
-		labels:       labels,
-		minBufferLen: minBufferLen,
+// setStack 修改stack之后更新path和scanner
+func (s *labelStream) setStack(stack []*SLabel) {
+	s.stack = stack
+	names := make([]string, 0, len(stack))
+	for _, lb := range stack {
+		names = append(names, lb.Name)
 	}
+	s.path = strings.Join(names, "/")
+	s.scanner.reset(s.nestedTokens())
 }
 
-func (s *labelStream) cutOverflowBuffer() (label string, chunk string) {
-	if utf8.RuneCountInString(s.buffer) <= s.minBufferLen {
-		return "", ""
+func (s *labelStream) nestedChunk(chunk string) LabeledChunk {
+	if len(s.stack) == 0 {
+		return LabeledChunk{Chunk: chunk}
 	}
+	return LabeledChunk{Label: s.stack[len(s.stack)-1].Name, Path: s.path, Chunk: chunk}
+}
 
-	if s.currentLabel != nil {
-		if s.currentLabel.EndToken == "" {
-			chunk := s.buffer
-			s.minBufferLen = 0
-			s.buffer = ""
-			return s.currentLabel.Name, chunk
-		}
-		index := strings.Index(s.buffer, s.currentLabel.EndToken)
-		if index >= 0 {
-			chunkBeforeToken := s.buffer[:index]
-			s.buffer = s.buffer[index:]
-			lastLabel := s.currentLabel
-			s.currentLabel = nil
-			return lastLabel.Name, chunkBeforeToken
+// cutNested 嵌套模式下处理buffer中的数据，返回的Chunk为空表示需要更多的上游数据
+func (s *labelStream) cutNested() (LabeledChunk, error) {
+	if !s.started {
+		s.started = true
+		if i := slices.IndexFunc(s.labels, func(lb SLabel) bool { return lb.StartToken == "" }); i >= 0 {
+			s.setStack([]*SLabel{&s.labels[i]})
 		}
 	}
-
-	for i, lb := range s.labels {
-		index := strings.Index(s.buffer, lb.StartToken)
-		if index == -1 {
-			continue
+	for {
+		safe, match, found := s.scanner.scan(s.eof)
+		if !found {
+			return s.nestedChunk(s.scanner.cut(safe)), nil
 		}
-		chunkBeforeToken := s.buffer[:index]
-		s.buffer = s.buffer[index+len(lb.StartToken):]
-
-		lbCopy := lb
-		s.currentLabel = &lbCopy
-		s.labels = slices.Delete(s.labels, i, i+1)
-		if s.currentLabel.EndToken == "" { // 这个label没有endToken，所以后面所有的chunk都属于这个label
-			s.minBufferLen = 0
+		if match.start > 0 { // 先输出token之前的文本
+			return s.nestedChunk(s.scanner.cut(match.start)), nil
 		}
-		if len(chunkBeforeToken) > 0 {
-			return "", chunkBeforeToken
-		} else {
-			return s.cutOverflowBuffer()
+		token := s.scanner.cut(match.end)
+		switch t := s.actions[match.token]; t.action {
+		case labelOpen:
+			s.setStack(append(s.stack, &s.labels[t.label]))
+		case labelClose:
+			s.setStack(s.stack[:t.label])
+		case labelMismatch:
+			s.err = fmt.Errorf("%w: %q", ErrLabelMismatch, token)
+			return LabeledChunk{}, s.err
 		}
 	}
+}
+
+// tokens 当前需要匹配的token：在label内时是该label的endToken和剩余label的startToken，否则只有剩余label的startToken
+func (s *labelStream) tokens() []string {
+	tokens := make([]string, 0, len(s.labels)+1)
+	if s.currentLabel != nil {
+		tokens = append(tokens, s.currentLabel.EndToken)
+	}
+	for _, lb := range s.labels {
+		tokens = append(tokens, lb.StartToken)
+	}
+	return tokens
+}
+
+func (s *labelStream) currentName() string {
+	if s.currentLabel == nil {
+		return ""
+	}
+	return s.currentLabel.Name
+}
+
+// enter 进入第i个label，startToken已经从buffer中移除
+func (s *labelStream) enter(i int) {
+	lb := s.labels[i]
+	s.currentLabel = &lb
+	s.labels = slices.Delete(s.labels, i, i+1)
+	s.scanner.reset(s.tokens())
+}
 
-	index := utf8.RuneCountInString(s.buffer) - s.minBufferLen
+func (s *labelStream) cutOverflowBuffer() (label string, chunk string) {
+	for {
+		if s.currentLabel == nil {
+			// startToken为空的label直接开始
+			if i := slices.IndexFunc(s.labels, func(lb SLabel) bool { return lb.StartToken == "" }); i >= 0 {
+				s.enter(i)
+				continue
+			}
+		} else if s.currentLabel.EndToken == "" { // 这个label没有endToken，所以后面所有的chunk都属于这个label
+			return s.currentLabel.Name, s.scanner.cut(len(s.scanner.buf))
+		}
+
+		safe, match, found := s.scanner.scan(s.eof)
+		if !found {
+			return s.currentName(), s.scanner.cut(safe)
+		}
+		if s.currentLabel != nil && match.token == 0 { // 遇到了当前label的endToken，endToken留在buffer中
+			lastLabel := s.currentLabel
+			s.currentLabel = nil
+			chunk := s.scanner.cut(match.start)
+			s.scanner.reset(s.tokens())
+			if chunk != "" {
+				return lastLabel.Name, chunk
+			}
+			continue
+		}
 
-	if index > 0 {
-		bufferRunes := []rune(s.buffer)
-		beforeChunk := string(bufferRunes[:index])
-		s.buffer = string(bufferRunes[index:])
-		labelName := ""
+		// 遇到了某个label的startToken
+		i := match.token
 		if s.currentLabel != nil {
-			labelName = s.currentLabel.Name
+			i--
+		}
+		lastName := s.currentName()
+		chunk := s.scanner.cut(match.start)
+		s.scanner.cut(match.end - match.start)
+		s.enter(i)
+		if chunk != "" {
+			return lastName, chunk
 		}
-		return labelName, beforeChunk
 	}
-
-	return "", ""
 }
 
 // 需要注意的是:
-// - labels是从前往后匹配，所以越前面的label优先级越高，因此传入labels的顺序应该尽量和流式文本中出现的顺序一致
-// - 每个label只会使用一次，如果流式文本中需要2次，请使用不同的标签名，他们可以有相同的startToken和endToken，但是必须保证labels有序
+// - 最先出现的token优先，多个token从同一位置开始时，当前label的endToken优先，其次是越前面的label的startToken
+// - 每个label只会使用一次(嵌套模式除外，见WithNestedLabels)，如果流式文本中需要2次，请使用不同的标签名，他们可以有相同的startToken和endToken，但是必须保证labels有序
 // - 只有当labels的startToken之间没有重叠，labels才不用关注顺序
 // - startToken 不会输出，但是endToken可能会，这样做的目的是可以保证下一个label的startToken可以从当前label的endToken之前进行匹配
+// - 只有末尾可能是某个token前缀的文本才会被暂存，可以通过WithMaxHoldback限制暂存的时间
 func (s *labelStream) Recv() (LabeledChunk, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *labelStream) RecvContext(ctx context.Context) (LabeledChunk, error) {
 	for {
-		label, chunk := s.cutOverflowBuffer() // 先处理buffer中的数据
-		if chunk != "" {
+		if s.err != nil {
+			return LabeledChunk{}, s.err
+		}
+		if s.nested {
+			lc, err := s.cutNested()
+			if err != nil || lc.Chunk != "" {
+				return lc, err
+			}
+		} else if label, chunk := s.cutOverflowBuffer(); chunk != "" { // 先处理buffer中的数据
 			return LabeledChunk{Label: label, Chunk: chunk}, nil
 		}
+		if s.eof { // 上游已经读完了，buffer也处理完了
+			return LabeledChunk{}, io.EOF
+		}
 
-		upChunk, err := s.src.Recv()
-		if err == io.EOF { // 上游已经读完了，但是buffer中还有数据，需要处理
-			s.minBufferLen = 0
-			label, chunk := s.cutOverflowBuffer()
-			if chunk != "" {
-				return LabeledChunk{Label: label, Chunk: chunk}, nil
-			} else {
-				return LabeledChunk{}, io.EOF
+		upChunk, err := s.src.recv(ctx, len(s.scanner.buf) > 0)
+		if err == errHoldbackExpired { // 暂存超时，当作普通文本输出
+			if s.nested {
+				return s.nestedChunk(s.scanner.cut(len(s.scanner.buf))), nil
 			}
+			return LabeledChunk{Label: s.currentName(), Chunk: s.scanner.cut(len(s.scanner.buf))}, nil
+		} else if err == io.EOF { // 上游已经读完了，但是buffer中还有数据，需要处理
+			s.eof = true
+			continue
 		} else if err != nil {
 			return LabeledChunk{}, err
 		}
-		s.buffer += upChunk
+		s.scanner.write(upChunk)
 	}
 }
 
+func (s *labelStream) Close() error {
+	return s.src.close()
+}
+
 // checkLabels 检查label是否合法，比如label未命名（会和默认label冲突），或者不同label之间startToken有重叠，会导致label识别结果不确定
 func checkLabels(labels []SLabel) error {
 	if len(labels) == 0 {
@@ -142,10 +298,7 @@ func checkLabels(labels []SLabel) error {
 
 // Split 将流式文本按照label切分成多个流
 func (s *labelStream) Demux() map[string]Stream[string] {
-	labels := make([]string, len(s.labels))
-	for _, lb := range s.labels {
-		labels = append(labels, lb.Name)
-	}
+	labels := slices.Clone(s.names)
 
 	tmp := Demux(s, func(s LabeledChunk) string { return s.Label }, labels)
 	res := make(map[string]Stream[string], len(labels))
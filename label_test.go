@@ -1,7 +1,9 @@
 package streams
 
 import (
+	"errors"
 	"io"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -81,6 +83,33 @@ func TestLabelStream_Split(t *testing.T) {
 	})
 }
 
+func TestLabelStream_EarliestMatch(t *testing.T) {
+	src := FromSlice(strings.Split("x<B>b</B><A>a</A>", ""))
+	ls := NewLabelStream(src, []SLabel{
+		{Name: "A", StartToken: "<A>", EndToken: "</A>"},
+		{Name: "B", StartToken: "<B>", EndToken: "</B>"},
+	})
+	var got []LabeledChunk
+	Consume(ls, func(c LabeledChunk) error {
+		if n := len(got); n > 0 && got[n-1].Label == c.Label {
+			got[n-1].Chunk += c.Chunk
+		} else {
+			got = append(got, c)
+		}
+		return nil
+	})
+	want := []LabeledChunk{
+		{Label: "", Chunk: "x"},
+		{Label: "B", Chunk: "b"},
+		{Label: "", Chunk: "</B>"},
+		{Label: "A", Chunk: "a"},
+		{Label: "", Chunk: "</A>"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestLabelStream_Split_NoEndToken(t *testing.T) {
 	src := FromSlice([]string{"start", "<X>rest", "of", "stream"})
 	labels := []SLabel{
@@ -90,3 +119,92 @@ func TestLabelStream_Split_NoEndToken(t *testing.T) {
 	demux := ls.Demux()
 	expectStringStream(t, demux["X"], "restofstream", io.EOF)
 }
+
+func TestLabelStream_Nested(t *testing.T) {
+	labels := []SLabel{
+		{Name: "answer", StartToken: "<answer>", EndToken: "</answer>"},
+		{Name: "cite", StartToken: "<cite>", EndToken: "</cite>"},
+	}
+	collect := func(ls Stream[LabeledChunk]) ([]LabeledChunk, error) {
+		var got []LabeledChunk
+		err := Consume(ls, func(c LabeledChunk) error {
+			if n := len(got); n > 0 && got[n-1].Path == c.Path {
+				got[n-1].Chunk += c.Chunk
+			} else {
+				got = append(got, c)
+			}
+			return nil
+		})
+		return got, err
+	}
+
+	t.Run("repeat and nest", func(t *testing.T) {
+		src := FromSlice(strings.Split("a<answer>b<cite>c</cite>d</answer>e<cite>f</cite>", ""))
+		got, err := collect(NewLabelStream(src, labels, WithNestedLabels(LabelEndAsText)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []LabeledChunk{
+			{Chunk: "a"},
+			{Label: "answer", Path: "answer", Chunk: "b"},
+			{Label: "cite", Path: "answer/cite", Chunk: "c"},
+			{Label: "answer", Path: "answer", Chunk: "d"},
+			{Chunk: "e"},
+			{Label: "cite", Path: "cite", Chunk: "f"},
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("mismatch as text", func(t *testing.T) {
+		src := FromSlice([]string{"<answer>a</cite>b</answer>"})
+		got, err := collect(NewLabelStream(src, labels, WithNestedLabels(LabelEndAsText)))
+		want := []LabeledChunk{{Label: "answer", Path: "answer", Chunk: "a</cite>b"}}
+		if err != nil || !slices.Equal(got, want) {
+			t.Fatalf("got %v, %v; want %v", got, err, want)
+		}
+	})
+
+	t.Run("auto close", func(t *testing.T) {
+		src := FromSlice([]string{"<answer>a<cite>b</answer>c"})
+		got, err := collect(NewLabelStream(src, labels, WithNestedLabels(LabelEndAutoClose)))
+		want := []LabeledChunk{
+			{Label: "answer", Path: "answer", Chunk: "a"},
+			{Label: "cite", Path: "answer/cite", Chunk: "b"},
+			{Chunk: "c"},
+		}
+		if err != nil || !slices.Equal(got, want) {
+			t.Fatalf("got %v, %v; want %v", got, err, want)
+		}
+	})
+
+	t.Run("mismatch error", func(t *testing.T) {
+		src := FromSlice([]string{"a</cite>b"})
+		got, err := collect(NewLabelStream(src, labels, WithNestedLabels(LabelEndError)))
+		if !errors.Is(err, ErrLabelMismatch) {
+			t.Fatalf("expected ErrLabelMismatch, got %v", err)
+		}
+		if want := []LabeledChunk{{Chunk: "a"}}; !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("same start and end token", func(t *testing.T) {
+		src := FromSlice([]string{"a```go\n```b"})
+		ls := NewLabelStream(src, []SLabel{{Name: "code", StartToken: "```", EndToken: "```"}}, WithNestedLabels(LabelEndAsText))
+		expectStream(t, ls, []LabeledChunk{{Chunk: "a"}, {Label: "code", Path: "code", Chunk: "go\n"}, {Chunk: "b"}}, io.EOF)
+	})
+
+	t.Run("Demux by innermost label", func(t *testing.T) {
+		src := FromSlice(strings.Split("<answer>x<cite>1</cite>y</answer><cite>2</cite>", ""))
+		demux := NewLabelStream(src, labels, WithNestedLabels(LabelEndAsText)).Demux()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			expectStringStream(t, demux["answer"], "xy", io.EOF)
+		}()
+		expectStringStream(t, demux["cite"], "12", io.EOF)
+		<-done
+	})
+}
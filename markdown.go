@@ -0,0 +1,263 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MarkdownChunk NewMarkdownStream的输出
+type MarkdownChunk struct {
+	Text   string
+	InCode bool   // Text属于围栏代码块，包括开始和结束的围栏行
+	Fence  bool   // Text是代码块的开始或结束围栏行(的一部分)
+	Lang   string // 所在代码块的语言，即开始围栏行```后面的第一个单词
+}
+
+type markdownStream struct {
+	src holdbackReceiver
+
+	buf       string
+	lineStart bool // buf从一行的开头开始
+	inCode    bool
+	fenceChar byte // 当前代码块围栏的字符和长度
+	fenceLen  int
+	lang      string
+	fenceLine bool // 超时输出了不完整的开始围栏行，这一行剩下的部分也属于围栏行
+	expired   bool // 暂存超时，当前buffer按上游结束处理
+	eof       bool
+}
+
+// NewMarkdownStream 调整流式markdown的切分位置，避免渲染到一半的**粗体、[链接](地址或者代码块围栏时页面闪烁
+// 注意事项：
+// 1. 只暂存最早的没有闭合的行内结构(强调、删除线、行内代码、链接和图片)，行内结构不会跨行，遇到换行直接输出
+// 2. 行首可能是代码块围栏的文本会暂存到行尾，围栏行单独输出，代码块中的文本只在行首暂存，其余直接输出
+// 3. 所有输出拼起来和输入完全一致，可以通过WithMaxHoldback限制暂存的时间，超时的文本按普通文本输出
+func NewMarkdownStream(src Stream[string], opts ...ParserOption) *markdownStream {
+	return &markdownStream{src: newHoldbackReceiver(src, opts), lineStart: true}
+}
+
+func (s *markdownStream) Recv() (MarkdownChunk, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *markdownStream) RecvContext(ctx context.Context) (MarkdownChunk, error) {
+	for {
+		if c, ok := s.cutOverflowBuffer(); ok {
+			return c, nil
+		}
+		if s.eof {
+			return MarkdownChunk{}, io.EOF
+		}
+
+		s.expired = false
+		chunk, err := s.src.recv(ctx, s.buf != "")
+		if err == errHoldbackExpired {
+			s.expired = true
+			continue
+		} else if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return MarkdownChunk{}, err
+		}
+		s.buf += chunk
+	}
+}
+
+func (s *markdownStream) Close() error {
+	return s.src.close()
+}
+
+func (s *markdownStream) cut(n int, fence bool) MarkdownChunk {
+	text := s.buf[:n]
+	s.buf = s.buf[n:]
+	s.lineStart = strings.HasSuffix(text, "\n")
+	return MarkdownChunk{Text: text, InCode: s.inCode, Fence: fence, Lang: s.lang}
+}
+
+// cutOverflowBuffer 处理buffer中已经可以确定的数据，ok为false表示需要更多的上游数据
+func (s *markdownStream) cutOverflowBuffer() (c MarkdownChunk, ok bool) {
+	if s.buf == "" {
+		return MarkdownChunk{}, false
+	}
+	flush := s.eof || s.expired
+	line := s.buf // 当前行，有换行符时包括换行符
+	complete := false
+	if nl := strings.IndexByte(s.buf, '\n'); nl >= 0 {
+		line, complete = s.buf[:nl+1], true
+	}
+
+	if s.fenceLine {
+		s.fenceLine = !complete
+		return s.cut(len(line), true), true
+	}
+	if s.lineStart {
+		char, n, info, partial := parseFence(line)
+		if partial && !complete && !flush {
+			return MarkdownChunk{}, false
+		}
+		if !s.inCode && n >= 3 && (char == '~' || !strings.ContainsRune(info, '`')) {
+			if !complete && !flush {
+				return MarkdownChunk{}, false // 等待完整的语言
+			}
+			s.inCode, s.fenceChar, s.fenceLen = true, char, n
+			s.lang = ""
+			if fields := strings.Fields(info); len(fields) > 0 {
+				s.lang = fields[0]
+			}
+			s.fenceLine = !complete
+			return s.cut(len(line), true), true
+		}
+		if s.inCode && char == s.fenceChar && n >= s.fenceLen && strings.TrimSpace(info) == "" {
+			if !complete && !flush {
+				return MarkdownChunk{}, false // 后面可能还有其它字符，不一定是结束围栏
+			}
+			c = s.cut(len(line), true)
+			s.inCode, s.lang = false, ""
+			return c, true
+		}
+	}
+	if s.inCode || complete {
+		return s.cut(len(line), false), true
+	}
+	if n := markdownSafeLength(line, flush); n > 0 {
+		return s.cut(n, false), true
+	}
+	return MarkdownChunk{}, false
+}
+
+// parseFence 解析行首的代码块围栏，n是围栏字符的个数，info是围栏后面的部分，partial表示还不能确定是不是围栏
+func parseFence(line string) (char byte, n int, info string, partial bool) {
+	i := 0
+	for i < len(line) && i < 3 && line[i] == ' ' {
+		i++
+	}
+	if i == len(line) {
+		return 0, 0, "", true
+	}
+	char = line[i]
+	if char != '`' && char != '~' {
+		return 0, 0, "", false
+	}
+	j := i
+	for j < len(line) && line[j] == char {
+		j++
+	}
+	return char, j - i, line[j:], j == len(line) && j-i < 3
+}
+
+// markdownSafeLength 返回一行文本中可以输出的长度，最早的没有闭合的行内结构需要暂存，flush为true时全部输出
+func markdownSafeLength(line string, flush bool) int {
+	if flush {
+		return len(line)
+	}
+	const (
+		inlineNone = iota
+		inlineEmphasis
+		inlineCode
+		inlineLinkText
+		inlineLinkURL
+	)
+	kind, open, delim := inlineNone, 0, ""
+	for i := 0; i < len(line); {
+		c := line[i]
+		run := i + 1 // 连续相同字符的结尾
+		for run < len(line) && line[run] == c {
+			run++
+		}
+		switch kind {
+		case inlineNone:
+			switch {
+			case c == '\\':
+				if i+1 == len(line) {
+					return i
+				}
+				run = i + 2
+			case c == '`':
+				if run == len(line) {
+					return i // 后面可能还有`
+				}
+				kind, open, delim = inlineCode, i, line[i:run]
+			case c == '*' || c == '_' || c == '~':
+				if run == len(line) {
+					return i // 还不知道后面是不是空白
+				}
+				next, _ := utf8.DecodeRuneInString(line[run:])
+				intraword := c == '_' && i > 0 && isWordByte(line[i-1])
+				if !unicode.IsSpace(next) && !intraword && (c != '~' || run-i >= 2) {
+					kind, open, delim = inlineEmphasis, i, line[i:run]
+				}
+			case c == '[':
+				kind, open, run = inlineLinkText, i, i+1
+			case c == '!' && i+1 < len(line) && line[i+1] == '[':
+				kind, open, run = inlineLinkText, i, i+2
+			}
+		case inlineCode:
+			if c == '`' {
+				if run == len(line) {
+					return open
+				}
+				if run-i == len(delim) {
+					kind = inlineNone
+				}
+			}
+		case inlineEmphasis:
+			if c == delim[0] && run-i >= len(delim) && !unicode.IsSpace(rune(line[i-1])) {
+				kind, run = inlineNone, i+len(delim)
+			}
+		case inlineLinkText:
+			run = i + 1
+			if c == '\\' {
+				run = i + 2
+			} else if c == ']' {
+				if i+1 == len(line) {
+					return open
+				}
+				if line[i+1] == '(' {
+					kind, run = inlineLinkURL, i+2
+				} else {
+					kind = inlineNone // 只是方括号，比如[1]
+				}
+			}
+		case inlineLinkURL:
+			run = i + 1
+			if c == ')' {
+				kind = inlineNone
+			}
+		}
+		i = run
+	}
+	if kind != inlineNone {
+		return open
+	}
+	return len(line)
+}
+
+func isWordByte(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// Demux 将代码块按语言拆分到单独的流中，返回的map中""是代码块之外的文本，langs中的每个语言对应该语言所有代码块的内容(不包括围栏行)
+// 不在langs中的代码块原样留在""中，""不能作为语言；和labelStream.Demux一样，只消费其中一部分流时需要Close其它流
+func (s *markdownStream) Demux(langs ...string) map[string]Stream[string] {
+	langs = slices.DeleteFunc(slices.Clone(langs), func(lang string) bool { return lang == "" })
+	const dropped = "\x00fence" // 被拆分出去的代码块的围栏行，不属于任何流
+	tmp := Demux(s, func(c MarkdownChunk) string {
+		if !c.InCode || !slices.Contains(langs, c.Lang) {
+			return ""
+		}
+		if c.Fence {
+			return dropped
+		}
+		return c.Lang
+	}, langs)
+	res := make(map[string]Stream[string], len(tmp))
+	for k, v := range tmp {
+		res[k] = Map(v, func(c MarkdownChunk) string { return c.Text })
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package streams
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMarkdownStream(t *testing.T) {
+	text := func(s string) MarkdownChunk { return MarkdownChunk{Text: s} }
+	tests := []struct {
+		name  string
+		input []string
+		want  []MarkdownChunk
+	}{
+		{"bold", []string{"hello **wor", "ld** !"}, []MarkdownChunk{text("hello "), text("**world** !")}},
+		{"link", []string{"see [doc](http", "://x.y) now"}, []MarkdownChunk{text("see "), text("[doc](http://x.y) now")}},
+		{"brackets without url", []string{"ref [1", "] ok"}, []MarkdownChunk{text("ref "), text("[1] ok")}},
+		{"inline code", []string{"run `a*", "b` now"}, []MarkdownChunk{text("run "), text("`a*b` now")}},
+		{"not emphasis", []string{"2 * 3 = 6, snake_case"}, []MarkdownChunk{text("2 * 3 = 6, snake_case")}},
+		{"released at newline", []string{"a **b", "\nc"}, []MarkdownChunk{text("a "), text("**b\n"), text("c")}},
+		{"escaped", []string{`a \*b`}, []MarkdownChunk{text(`a \*b`)}},
+		{"code block", []string{"intro\n``", "`go run\nfmt.Print(", "\"**\")\n``", "`\nbye"}, []MarkdownChunk{
+			text("intro\n"),
+			{Text: "```go run\n", InCode: true, Fence: true, Lang: "go"},
+			{Text: "fmt.Print(", InCode: true, Lang: "go"},
+			{Text: "\"**\")\n", InCode: true, Lang: "go"},
+			{Text: "```\n", InCode: true, Fence: true, Lang: "go"},
+			text("bye"),
+		}},
+		{"fence needs same char and length", []string{"~~~~\n```\n~~~\n~~~~\n"}, []MarkdownChunk{
+			{Text: "~~~~\n", InCode: true, Fence: true},
+			{Text: "```\n", InCode: true},
+			{Text: "~~~\n", InCode: true},
+			{Text: "~~~~\n", InCode: true, Fence: true},
+		}},
+		{"unclosed at eof", []string{"a **b"}, []MarkdownChunk{text("a "), text("**b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStream(t, NewMarkdownStream(FromSlice(tt.input)), tt.want, io.EOF)
+		})
+	}
+
+	t.Run("streams text before construct", func(t *testing.T) {
+		s := NewMarkdownStream(blockingStream("plain and [li", "nk](u)"))
+		if c, err := s.Recv(); c != text("plain and ") || err != nil {
+			t.Fatalf("Recv() = %+v, %v", c, err)
+		}
+	})
+
+	t.Run("max holdback", func(t *testing.T) {
+		s := NewMarkdownStream(slowStream("a **b", 100*time.Millisecond, "**"), WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, s, []MarkdownChunk{text("a "), text("**b"), text("**")}, io.EOF)
+	})
+
+	t.Run("Demux", func(t *testing.T) {
+		src := FromSlice([]string{"看代码：\n```json\n{\"a\":", "1}\n```\n```sh\nls\n```\n完"})
+		res := NewMarkdownStream(src).Demux("json")
+		expectStringStream(t, res["json"], "{\"a\":1}\n", io.EOF)
+		expectStringStream(t, res[""], "看代码：\n```sh\nls\n```\n完", io.EOF)
+	})
+}
@@ -1,6 +1,7 @@
 package streams
 
 import (
+	"context"
 	"time"
 )
 
@@ -21,12 +22,12 @@ func ThrottleMerge[T any](s Stream[T], merge func(packetA, packetB T) (merged T,
 		return lastBuf
 	}
 
-	return FromFunc(func() (T, error) {
+	return newFuncStream(func(ctx context.Context) (T, error) {
 		if bufErr != nil {
 			return zero, bufErr
 		}
 		if buf == nil {
-			packet, err := s.Recv()
+			packet, err := RecvContext(ctx, s)
 			if err != nil {
 				return packet, err
 			}
@@ -36,7 +37,10 @@ func ThrottleMerge[T any](s Stream[T], merge func(packetA, packetB T) (merged T,
 			if time.Since(lastSendAt) > throttleDuration {
 				return sendBuf(), nil
 			} else {
-				packet, err := s.Recv()
+				packet, err := RecvContext(ctx, s)
+				if isContextErr(ctx, err) { // ctx取消只影响本次调用，buf保留到下次发送
+					return zero, err
+				}
 				if err != nil {
 					bufErr = err
 					return sendBuf(), nil
@@ -51,7 +55,7 @@ func ThrottleMerge[T any](s Stream[T], merge func(packetA, packetB T) (merged T,
 				}
 			}
 		}
-	})
+	}, closerOf(s))
 }
 
 // ThrottleMerge2 每隔指定时间，将流里面的多个包进行聚合成新的包，然后再发送给下游。用于sse攒包推送
@@ -71,17 +75,21 @@ func ThrottleMerge2[T any](s Stream[T], merge func(packets []T) []T, throttleDur
 		}
 	}
 
-	receiveOrigBuf := func() {
-		packet, err := s.Recv()
+	receiveOrigBuf := func(ctx context.Context) error {
+		packet, err := RecvContext(ctx, s)
+		if isContextErr(ctx, err) {
+			return err
+		}
 		if err != nil {
 			mergeOrigBuf()
 			sendErr = err
 		} else {
 			origBuf = append(origBuf, packet)
 		}
+		return nil
 	}
 
-	return FromFunc(func() (T, error) {
+	return newFuncStream(func(ctx context.Context) (T, error) {
 		for {
 			if len(sendBuf) > 0 { // 如果有缓存，直接发送
 				send := sendBuf[0]
@@ -94,12 +102,14 @@ func ThrottleMerge2[T any](s Stream[T], merge func(packets []T) []T, throttleDur
 
 			if time.Since(lastMergeAt) > throttleDuration {
 				if len(origBuf) == 0 {
-					receiveOrigBuf()
+					if err := receiveOrigBuf(ctx); err != nil {
+						return zero, err
+					}
 				}
 				mergeOrigBuf()
-			} else {
-				receiveOrigBuf()
+			} else if err := receiveOrigBuf(ctx); err != nil {
+				return zero, err
 			}
 		}
-	})
+	}, closerOf(s))
 }
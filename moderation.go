@@ -0,0 +1,243 @@
+package streams
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrModerationBlocked 流因为命中敏感词被终止，具体信息见ModerationError
+var ErrModerationBlocked = errors.New("streams: blocked by moderation")
+
+// ModerationError 命中ModerationBlock时返回的错误，errors.Is(err, ErrModerationBlocked)为true
+type ModerationError struct {
+	Word       string
+	SafePrefix string // 终止之前已经输出的全部文本
+}
+
+func (e *ModerationError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrModerationBlocked, e.Word)
+}
+
+func (e *ModerationError) Unwrap() error {
+	return ErrModerationBlocked
+}
+
+// ModerationAction 命中敏感词之后的处理方式
+type ModerationAction int
+
+const (
+	ModerationMask   ModerationAction = iota // 替换为等长(字符数)的掩码
+	ModerationRemove                         // 删除
+	ModerationBlock                          // 终止流，返回*ModerationError
+	ModerationAllow                          // 原样输出，用于校验之后发现是误判
+)
+
+// ModerationHit 交给ModerationVerifier校验的命中
+type ModerationHit struct {
+	Word   string
+	Before string // 命中之前已经输出的全部文本
+}
+
+// ModerationVerifier 校验命中的敏感词，返回最终的处理方式，通常是调用审核服务
+// 返回错误时流会以该错误终止
+type ModerationVerifier func(ctx context.Context, hit ModerationHit) (ModerationAction, error)
+
+// ModerationDict 编译好的敏感词词典，构建一次之后可以被多个流并发使用
+type ModerationDict struct {
+	matcher *tokenMatcher
+}
+
+// NewModerationDict 构建词典，空字符串会被忽略，多个词从同一位置开始时更长的词优先
+func NewModerationDict(words []string) *ModerationDict {
+	words = slices.DeleteFunc(slices.Clone(words), func(w string) bool { return w == "" })
+	slices.SortFunc(words, func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	return &ModerationDict{matcher: newTokenMatcher(slices.Compact(words))}
+}
+
+type ModerationOption func(*moderationOptions)
+
+type moderationOptions struct {
+	action     ModerationAction
+	mask       rune
+	verifier   ModerationVerifier
+	parserOpts []ParserOption
+}
+
+// WithModerationAction 没有ModerationVerifier时命中之后的处理方式，默认是ModerationMask
+func WithModerationAction(action ModerationAction) ModerationOption {
+	return func(o *moderationOptions) {
+		o.action = action
+	}
+}
+
+// WithModerationMask ModerationMask使用的掩码字符，默认是*
+func WithModerationMask(mask rune) ModerationOption {
+	return func(o *moderationOptions) {
+		o.mask = mask
+	}
+}
+
+// WithModerationVerifier 每个命中都交给verifier决定处理方式，校验期间命中的词和它后面的文本都不会输出
+func WithModerationVerifier(verifier ModerationVerifier) ModerationOption {
+	return func(o *moderationOptions) {
+		o.verifier = verifier
+	}
+}
+
+// WithModerationParserOptions 底层文本解析的选项，比如WithMaxHoldback
+// 注意：暂存超时输出的敏感词前缀不会再和后面的文本一起匹配
+func WithModerationParserOptions(opts ...ParserOption) ModerationOption {
+	return func(o *moderationOptions) {
+		o.parserOpts = append(o.parserOpts, opts...)
+	}
+}
+
+type moderationResult struct {
+	action ModerationAction
+	err    error
+}
+
+type moderationStream struct {
+	src  holdbackReceiver
+	opts moderationOptions
+
+	scanner   tokenScanner
+	output    strings.Builder // 已经输出的文本
+	hit       string          // 正在校验的词
+	verifying chan moderationResult
+	closed    context.Context // Close之后取消，用于取消正在进行的校验
+	cancel    context.CancelFunc
+	eof       bool
+	err       error
+}
+
+// Moderate 按词典过滤流中的敏感词，敏感词可以跨chunk
+// 注意事项：
+// 1. 多个敏感词之间，最早出现的优先，从同一位置开始时更长的优先；只有末尾可能是某个敏感词前缀的文本才会被暂存
+// 2. 匹配按字节进行，区分大小写
+// 3. ModerationBlock会立即关闭上游，返回的*ModerationError中包含已经输出的全部文本
+// 4. verifier在后台执行，RecvContext的ctx取消时不会中断校验，结果留给下一次读取；Close会取消正在进行的校验
+func Moderate(src Stream[string], dict *ModerationDict, opts ...ModerationOption) Stream[string] {
+	o := moderationOptions{mask: '*'}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	closed, cancel := context.WithCancel(context.Background())
+	return &moderationStream{
+		src:     newHoldbackReceiver(src, o.parserOpts),
+		opts:    o,
+		scanner: tokenScanner{matcher: dict.matcher},
+		closed:  closed,
+		cancel:  cancel,
+	}
+}
+
+func (s *moderationStream) Recv() (string, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *moderationStream) RecvContext(ctx context.Context) (string, error) {
+	for {
+		if s.err != nil {
+			return "", s.err
+		}
+		if s.verifying != nil {
+			select {
+			case r := <-s.verifying:
+				s.verifying = nil
+				if r.err != nil {
+					s.err = r.err
+					continue
+				}
+				if text := s.apply(s.hit, r.action); text != "" {
+					return text, nil
+				}
+				continue
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		safe, match, found := s.scanner.scan(s.eof)
+		if found {
+			if match.start > 0 {
+				return s.emit(s.scanner.cut(match.start)), nil
+			}
+			word := s.scanner.cut(match.end)
+			if s.opts.verifier != nil {
+				s.verify(ctx, word)
+				continue
+			}
+			if text := s.apply(word, s.opts.action); text != "" {
+				return text, nil
+			}
+			continue
+		}
+		if safe > 0 {
+			return s.emit(s.scanner.cut(safe)), nil
+		}
+		if s.eof {
+			return "", io.EOF
+		}
+
+		chunk, err := s.src.recv(ctx, len(s.scanner.buf) > 0)
+		if err == errHoldbackExpired {
+			return s.emit(s.scanner.cut(len(s.scanner.buf))), nil
+		} else if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		s.scanner.write(chunk)
+	}
+}
+
+// verify 在后台校验word，不受本次RecvContext的ctx取消的影响
+func (s *moderationStream) verify(ctx context.Context, word string) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(s.closed, cancel)
+	hit := ModerationHit{Word: word, Before: s.output.String()}
+	verifying := make(chan moderationResult, 1)
+	s.hit, s.verifying = word, verifying
+	go func() {
+		defer cancel()
+		defer stop()
+		action, err := s.opts.verifier(ctx, hit)
+		verifying <- moderationResult{action: action, err: err}
+	}()
+}
+
+// apply 按action处理命中的word，返回需要输出的文本
+func (s *moderationStream) apply(word string, action ModerationAction) string {
+	switch action {
+	case ModerationMask:
+		return s.emit(strings.Repeat(string(s.opts.mask), utf8.RuneCountInString(word)))
+	case ModerationRemove:
+		return ""
+	case ModerationAllow:
+		return s.emit(word)
+	default:
+		s.err = &ModerationError{Word: word, SafePrefix: s.output.String()}
+		_ = s.src.close()
+		return ""
+	}
+}
+
+func (s *moderationStream) emit(text string) string {
+	s.output.WriteString(text)
+	return text
+}
+
+func (s *moderationStream) Close() error {
+	s.cancel()
+	return s.src.close()
+}
@@ -0,0 +1,99 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestModerate(t *testing.T) {
+	dict := NewModerationDict([]string{"坏蛋", "坏", "bad", "", "badly"})
+	tests := []struct {
+		name  string
+		input []string
+		opts  []ModerationOption
+		want  string
+	}{
+		{"mask across chunks", []string{"你这个坏", "蛋！"}, nil, "你这个**！"},
+		{"longest word wins", []string{"so bad", "ly done, bad."}, nil, "so ***** done, ***."},
+		{"custom mask", []string{"坏人"}, []ModerationOption{WithModerationMask('×')}, "×人"},
+		{"remove", []string{"a bad", " b"}, []ModerationOption{WithModerationAction(ModerationRemove)}, "a  b"},
+		{"no hit", []string{"ba", "nana"}, nil, "banana"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStringStream(t, Moderate(FromSlice(tt.input), dict, tt.opts...), tt.want, io.EOF)
+		})
+	}
+
+	t.Run("block", func(t *testing.T) {
+		src, closed := trackClose(FromSlice([]string{"hello, ba", "d guy"}))
+		s := Moderate(src, dict, WithModerationAction(ModerationBlock))
+		expectStringStream(t, s, "hello, ", ErrModerationBlocked)
+		_, err := s.Recv()
+		var merr *ModerationError
+		if !errors.As(err, &merr) || merr.Word != "bad" || merr.SafePrefix != "hello, " {
+			t.Fatalf("err = %v", err)
+		}
+		if closed.Load() != 1 {
+			t.Fatal("upstream was not closed")
+		}
+	})
+
+	t.Run("verifier", func(t *testing.T) {
+		var hits []ModerationHit
+		verifier := func(ctx context.Context, hit ModerationHit) (ModerationAction, error) {
+			hits = append(hits, hit)
+			if hit.Word == "坏" {
+				return ModerationAllow, nil
+			}
+			return ModerationMask, nil
+		}
+		s := Moderate(FromSlice([]string{"坏了，bad"}), dict, WithModerationVerifier(verifier))
+		expectStringStream(t, s, "坏了，***", io.EOF)
+		if len(hits) != 2 || hits[1].Before != "坏了，" {
+			t.Fatalf("hits = %+v", hits)
+		}
+	})
+
+	t.Run("verifier error", func(t *testing.T) {
+		errVerify := errors.New("verify failed")
+		s := Moderate(FromSlice([]string{"a bad"}), dict, WithModerationVerifier(func(context.Context, ModerationHit) (ModerationAction, error) {
+			return 0, errVerify
+		}))
+		expectStringStream(t, s, "a ", errVerify)
+	})
+
+	t.Run("slow verifier keeps result after ctx cancel", func(t *testing.T) {
+		s := Moderate(FromSlice([]string{"bad!"}), dict, WithModerationVerifier(func(ctx context.Context, _ ModerationHit) (ModerationAction, error) {
+			time.Sleep(50 * time.Millisecond)
+			return ModerationRemove, ctx.Err()
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := RecvContext(ctx, s); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v", err)
+		}
+		expectStringStream(t, s, "!", io.EOF)
+	})
+
+	t.Run("Close cancels verifier", func(t *testing.T) {
+		canceled := make(chan struct{})
+		s := Moderate(FromSlice([]string{"bad"}), dict, WithModerationVerifier(func(ctx context.Context, _ ModerationHit) (ModerationAction, error) {
+			<-ctx.Done()
+			close(canceled)
+			return 0, ctx.Err()
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, _ = RecvContext(ctx, s)
+		_ = Close(s)
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("verifier was not canceled")
+		}
+	})
+}
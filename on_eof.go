@@ -1,13 +1,16 @@
 package streams
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 func OnEOF[T any](src Stream[T], f func()) Stream[T] {
-	return FromFunc(func() (T, error) {
-		p, err := src.Recv()
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		p, err := RecvContext(ctx, src)
 		if err == io.EOF {
 			f()
 		}
 		return p, err
-	})
+	}, closerOf(src))
 }
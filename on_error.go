@@ -0,0 +1,65 @@
+package streams
+
+import (
+	"context"
+	"io"
+)
+
+// Catch 上游遇到非io.EOF的错误时，用handler返回的流替换上游继续输出，原上游会被Close
+// 注意事项：
+// 1. 只会替换一次，替换后的流再出错时错误会直接返回，需要多级降级时可以嵌套Catch
+// 2. handler返回nil表示不处理这个错误，错误会原样返回
+// 3. ctx取消导致的错误不会被处理
+func Catch[T any](src Stream[T], handler func(err error) Stream[T]) Stream[T] {
+	src = avoidNil(src)
+	caught := false
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		v, err := RecvContext(ctx, src)
+		if err == nil || err == io.EOF || caught || isContextErr(ctx, err) {
+			return v, err
+		}
+		replacement := handler(err)
+		if replacement == nil {
+			return v, err
+		}
+		caught = true
+		Close(src)
+		src = replacement
+		return RecvContext(ctx, src)
+	}, func() error {
+		return Close(src)
+	})
+}
+
+// OnErrorResume 上游遇到非io.EOF的错误时，改为输出fallback，是Catch的简化版
+// 注意：上游正常结束时fallback不会被消费，Close时会一起关闭
+func OnErrorResume[T any](src Stream[T], fallback Stream[T]) Stream[T] {
+	fallback = avoidNil(fallback)
+	resumed := false
+	s := Catch(src, func(err error) Stream[T] {
+		resumed = true
+		return fallback
+	})
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		return RecvContext(ctx, s)
+	}, func() error {
+		if resumed {
+			return Close(s)
+		}
+		return closeAll([]Stream[T]{s, fallback})
+	})
+}
+
+// OnErrorReturn 上游遇到非io.EOF的错误时，输出fn返回的数据作为最后一个数据，然后正常结束
+func OnErrorReturn[T any](src Stream[T], fn func(err error) T) Stream[T] {
+	return Catch(src, func(err error) Stream[T] {
+		return FromSlice([]T{fn(err)})
+	})
+}
+
+// IgnoreErrors 将上游非io.EOF的错误视为正常结束，用于尽力而为的旁路流，比如日志、埋点
+func IgnoreErrors[T any](src Stream[T]) Stream[T] {
+	return Catch(src, func(err error) Stream[T] {
+		return Empty[T]()
+	})
+}
@@ -0,0 +1,81 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCatch(t *testing.T) {
+	boom := errors.New("boom")
+	failing := func() Stream[string] {
+		return Concat(FromSlice([]string{"a", "b"}), FromErr[string](boom))
+	}
+
+	t.Run("switch to fallback", func(t *testing.T) {
+		src, closed := trackClose(failing())
+		var caught error
+		s := Catch(src, func(err error) Stream[string] {
+			caught = err
+			return FromSlice([]string{"fallback"})
+		})
+		expectStream(t, s, []string{"a", "b", "fallback"}, io.EOF)
+		if caught != boom || closed.Load() != 1 {
+			t.Fatalf("caught %v, closed %d times", caught, closed.Load())
+		}
+	})
+
+	t.Run("nil handler result keeps the error", func(t *testing.T) {
+		s := Catch(failing(), func(err error) Stream[string] { return nil })
+		expectStream(t, s, []string{"a", "b"}, boom)
+	})
+
+	t.Run("only catch once", func(t *testing.T) {
+		s := Catch(failing(), func(err error) Stream[string] { return failing() })
+		expectStream(t, s, []string{"a", "b", "a", "b"}, boom)
+	})
+
+	t.Run("EOF and context errors are not caught", func(t *testing.T) {
+		handler := func(err error) Stream[string] {
+			t.Fatalf("unexpected catch %v", err)
+			return nil
+		}
+		expectStream(t, Catch(FromSlice([]string{"a"}), handler), []string{"a"}, io.EOF)
+		expectCanceledPromptly(t, Catch(blockingStream[string](), handler))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := RecvContext(ctx, Catch(FromSlice([]string{"a"}), handler)); err != context.Canceled {
+			t.Fatalf("expected Canceled, got %v", err)
+		}
+	})
+}
+
+func TestOnErrorResume(t *testing.T) {
+	t.Run("resume with fallback", func(t *testing.T) {
+		s := OnErrorResume(Concat(FromSlice([]string{"a"}), FromErr[string](errors.New("boom"))), FromSlice([]string{"fallback"}))
+		expectStream(t, s, []string{"a", "fallback"}, io.EOF)
+	})
+
+	t.Run("unused fallback is closed", func(t *testing.T) {
+		fallback, closed := trackClose(FromSlice([]string{"fallback"}))
+		s := OnErrorResume(FromSlice([]string{"a"}), fallback)
+		expectStream(t, s, []string{"a"}, io.EOF)
+		Close(s)
+		if closed.Load() != 1 {
+			t.Fatal("fallback was not closed")
+		}
+	})
+}
+
+func TestOnErrorReturn(t *testing.T) {
+	s := OnErrorReturn(Concat(FromSlice([]string{"a"}), FromErr[string](errors.New("boom"))), func(err error) string {
+		return "error: " + err.Error()
+	})
+	expectStream(t, s, []string{"a", "error: boom"}, io.EOF)
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	s := IgnoreErrors(Concat(FromSlice([]int{1, 2}), FromErr[int](errors.New("boom")), FromSlice([]int{3})))
+	expectStream(t, s, []int{1, 2}, io.EOF)
+}
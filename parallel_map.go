@@ -0,0 +1,158 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type mapResult[R any] struct {
+	val R
+	err error
+}
+
+type parallelMapStream[T any, R any] struct {
+	src     Stream[T]
+	fn      func(context.Context, T) (R, error)
+	ordered bool
+
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	sem       chan struct{}          // 已从上游取出但还没交给下游的数据个数，即预取上限
+	slots     chan chan mapResult[R] // 有序模式：按上游顺序排列的结果槽
+	results   chan mapResult[R]      // 无序模式：先算完的先发
+	pending   chan mapResult[R]      // 有序模式：下游等待中被ctx打断的结果槽，下次继续等
+	err       error                  // 结束之后Recv都返回这个错误
+}
+
+// ParallelMap 并发地对流中的元素执行fn，最多预取workers个元素，按上游的顺序输出结果
+// 注意事项：
+// 1. 首次Recv时才会起goroutine读取上游
+// 2. fn返回错误时，排在它前面的结果会正常输出，之后输出该错误，取消其他还在执行的fn并关闭上游
+// 3. 没有消费完的流需要Close，否则读取上游的goroutine会阻塞到上游结束
+func ParallelMap[T any, R any](src Stream[T], workers int, fn func(ctx context.Context, t T) (R, error)) Stream[R] {
+	return newParallelMapStream(src, workers, fn, true)
+}
+
+// ParallelMapUnordered 同ParallelMap，但结果按完成的先后顺序输出
+func ParallelMapUnordered[T any, R any](src Stream[T], workers int, fn func(ctx context.Context, t T) (R, error)) Stream[R] {
+	return newParallelMapStream(src, workers, fn, false)
+}
+
+func newParallelMapStream[T any, R any](src Stream[T], workers int, fn func(context.Context, T) (R, error), ordered bool) *parallelMapStream[T, R] {
+	workers = max(workers, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	return &parallelMapStream[T, R]{
+		src:     avoidNil(src),
+		fn:      fn,
+		ordered: ordered,
+		ctx:     ctx,
+		cancel:  cancel,
+		sem:     make(chan struct{}, workers),
+		slots:   make(chan chan mapResult[R], workers+1),
+		results: make(chan mapResult[R], workers),
+	}
+}
+
+// feed 从上游读取数据并分发给worker，上游结束时把结束的错误放到最后
+func (s *parallelMapStream[T, R]) feed() {
+	var wg sync.WaitGroup
+	for {
+		select {
+		case s.sem <- struct{}{}:
+		case <-s.ctx.Done():
+			return
+		}
+		v, err := RecvContext(s.ctx, s.src)
+		if isContextErr(s.ctx, err) {
+			return
+		}
+		if err != nil {
+			if s.ordered {
+				slot := make(chan mapResult[R], 1)
+				slot <- mapResult[R]{err: err}
+				s.slots <- slot // 容量多留了一个，不会阻塞
+			} else {
+				wg.Wait() // 等已经在执行的fn都输出之后再输出结束的错误
+				select {
+				case s.results <- mapResult[R]{err: err}:
+				case <-s.ctx.Done():
+				}
+			}
+			return
+		}
+
+		out := s.results
+		if s.ordered {
+			out = make(chan mapResult[R], 1)
+			s.slots <- out
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := s.fn(s.ctx, v)
+			select {
+			case out <- mapResult[R]{val: r, err: err}:
+			case <-s.ctx.Done():
+			}
+		}()
+	}
+}
+
+func (s *parallelMapStream[T, R]) Recv() (R, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *parallelMapStream[T, R]) RecvContext(ctx context.Context) (R, error) {
+	var zero R
+	if s.err != nil {
+		return zero, s.err
+	}
+	if s.ctx.Err() != nil {
+		return zero, ErrClosed
+	}
+	s.startOnce.Do(func() { go s.feed() })
+
+	out := s.results
+	if s.ordered {
+		if s.pending == nil {
+			select {
+			case s.pending = <-s.slots:
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case <-s.ctx.Done():
+				return zero, ErrClosed
+			}
+		}
+		out = s.pending
+	}
+
+	var r mapResult[R]
+	select {
+	case r = <-out:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-s.ctx.Done():
+		return zero, ErrClosed
+	}
+	s.pending = nil
+	<-s.sem
+
+	if r.err != nil {
+		s.err = r.err
+		if r.err == io.EOF {
+			s.cancel()
+		} else {
+			_ = s.Close() // 短路：关闭上游，取消还在执行的fn
+		}
+		return zero, r.err
+	}
+	return r.val, nil
+}
+
+// Close 停止所有goroutine并关闭上游
+func (s *parallelMapStream[T, R]) Close() error {
+	s.cancel()
+	return Close(s.src)
+}
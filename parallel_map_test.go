@@ -0,0 +1,106 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelMap(t *testing.T) {
+	slowDouble := func(ctx context.Context, i int) (int, error) {
+		time.Sleep(time.Duration(5-i) * 10 * time.Millisecond) // 越靠前越慢
+		return i * 2, nil
+	}
+
+	t.Run("ordered", func(t *testing.T) {
+		start := time.Now()
+		s := ParallelMap(FromSlice([]int{1, 2, 3, 4}), 4, slowDouble)
+		expectStream(t, s, []int{2, 4, 6, 8}, io.EOF)
+		if cost := time.Since(start); cost > 90*time.Millisecond {
+			t.Errorf("fn is not run concurrently, cost %s", cost)
+		}
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		s := ParallelMapUnordered(FromSlice([]int{1, 2, 3, 4}), 4, slowDouble)
+		got := slices.Collect(Iter(s))
+		if !slices.Equal(got, []int{8, 6, 4, 2}) {
+			t.Fatalf("got %v, want [8 6 4 2]", got)
+		}
+	})
+
+	t.Run("error short-circuits", func(t *testing.T) {
+		boom := errors.New("boom")
+		var canceled atomic.Bool
+		src, closed := trackClose(FromSlice([]int{1, 2, 3, 4}))
+		s := ParallelMap(src, 4, func(ctx context.Context, i int) (int, error) {
+			switch i {
+			case 2:
+				return 0, boom
+			case 4:
+				<-ctx.Done()
+				canceled.Store(true)
+				return 0, ctx.Err()
+			}
+			return i, nil
+		})
+		expectStream(t, s, []int{1}, boom)
+		time.Sleep(10 * time.Millisecond)
+		if !canceled.Load() {
+			t.Fatal("in-flight fn was not canceled")
+		}
+		if closed.Load() != 1 {
+			t.Fatal("upstream was not closed")
+		}
+	})
+
+	t.Run("upstream error keeps order", func(t *testing.T) {
+		boom := errors.New("boom")
+		s := ParallelMap(Concat(FromSlice([]int{1, 2}), FromErr[int](boom)), 2, slowDouble)
+		expectStream(t, s, []int{2, 4}, boom)
+	})
+
+	t.Run("prefetch is bounded", func(t *testing.T) {
+		var reads atomic.Int32
+		src := FromFunc(func() (int, error) {
+			return int(reads.Add(1)), nil
+		})
+		s := ParallelMap(src, 3, func(ctx context.Context, i int) (int, error) { return i, nil })
+		s.Recv()
+		time.Sleep(10 * time.Millisecond)
+		if n := reads.Load(); n > 4 {
+			t.Fatalf("read %d items from upstream, want at most 4", n)
+		}
+		Close(s)
+	})
+
+	t.Run("close stops workers", func(t *testing.T) {
+		src, closed := trackClose(blockingStream(1, 2))
+		started := make(chan struct{}, 2)
+		var canceled atomic.Int32
+		s := ParallelMapUnordered(src, 2, func(ctx context.Context, i int) (int, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			canceled.Add(1)
+			return 0, ctx.Err()
+		})
+		go s.Recv()
+		<-started
+		<-started
+		Close(s)
+		time.Sleep(10 * time.Millisecond)
+		if canceled.Load() != 2 || closed.Load() != 1 {
+			t.Fatalf("canceled %d workers and closed upstream %d times", canceled.Load(), closed.Load())
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		s := ParallelMap(blockingStream[int](), 2, slowDouble)
+		expectCanceledPromptly(t, s)
+		Close(s)
+	})
+}
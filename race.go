@@ -0,0 +1,229 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type raceResult[T any] struct {
+	attempt int
+	val     T
+	err     error
+}
+
+type raceStream[T any] struct {
+	factory     func(attempt int) (Stream[T], error)
+	maxAttempts int
+	delay       time.Duration // <=0时所有候选流同时开始，否则每隔delay启动一个备用流
+	all         []Stream[T]   // Race的全部候选流，Close时即使还没开始也要关闭
+
+	started bool
+	results chan raceResult[T]
+	running int
+	lastErr error
+	closed  chan struct{} // Close时关闭，让阻塞中的Recv返回
+
+	mu         sync.Mutex  // 保护下面的字段，Close可能和Recv并发
+	candidates []Stream[T] // 按attempt排列的候选流，创建失败的为nil
+	cancels    []context.CancelFunc
+	timer      *time.Timer
+	winner     Stream[T]
+	err        error
+}
+
+// Race 同时读取所有候选流的首包，哪个流最先读到数据就选择哪个流，其他流会被关闭
+// 首包之前就结束或出错的流会被淘汰，所有流都被淘汰时返回其中非io.EOF的错误，都是空流则返回io.EOF
+// 注意：Race会为每个候选流起一个goroutine读取首包，没有消费完的流需要Close
+func Race[T any](streams ...Stream[T]) Stream[T] {
+	streams = avoidNils(streams)
+	return &raceStream[T]{
+		factory:     func(attempt int) (Stream[T], error) { return streams[attempt], nil },
+		maxAttempts: len(streams),
+		all:         streams,
+		closed:      make(chan struct{}),
+	}
+}
+
+// Hedge 对冲请求：先通过factory创建一个流，如果delay内没有读到首包，再创建一个备用流同时等待，最多创建maxAttempts个流
+// 哪个流最先读到首包就选择哪个流，其他流会被关闭。候选流在首包之前就失败时，会立即创建下一个备用流
+// attempt从0开始，factory返回错误视为该候选流失败
+func Hedge[T any](factory func(attempt int) (Stream[T], error), delay time.Duration, maxAttempts int) Stream[T] {
+	return &raceStream[T]{
+		factory:     factory,
+		maxAttempts: max(maxAttempts, 1),
+		delay:       max(delay, time.Nanosecond),
+		closed:      make(chan struct{}),
+	}
+}
+
+// launch 创建并开始读取下一个候选流，已经Close的话关闭新创建的流
+func (r *raceStream[T]) launch() {
+	attempt := len(r.candidates) // candidates只在Recv中修改，这里不用加锁
+	s, err := r.factory(attempt)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isClosed() {
+		if err == nil {
+			Close(s)
+		}
+		return
+	}
+	if r.delay > 0 && attempt+1 < r.maxAttempts {
+		if r.timer == nil {
+			r.timer = time.NewTimer(r.delay)
+		} else {
+			r.timer.Reset(r.delay)
+		}
+	}
+	if err != nil {
+		r.candidates = append(r.candidates, nil)
+		r.cancels = append(r.cancels, func() {})
+		r.recordErr(err)
+		return
+	}
+	s = avoidNil(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.candidates = append(r.candidates, s)
+	r.cancels = append(r.cancels, cancel)
+	r.running++
+	go func() {
+		v, err := RecvContext(ctx, s)
+		r.results <- raceResult[T]{attempt: attempt, val: v, err: err} // 有足够的缓冲，不会阻塞
+	}()
+}
+
+func (r *raceStream[T]) isClosed() bool {
+	select {
+	case <-r.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordErr 记录被淘汰的候选流的错误，优先保留非io.EOF的错误
+func (r *raceStream[T]) recordErr(err error) {
+	if r.lastErr == nil || r.lastErr == io.EOF {
+		r.lastErr = err
+	}
+}
+
+// commit 选定胜出的候选流，关闭其他候选流，已经Close的话返回false
+func (r *raceStream[T]) commit(attempt int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isClosed() {
+		return false
+	}
+	r.winner = r.candidates[attempt]
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	for i, s := range r.candidates {
+		if i != attempt {
+			r.cancels[i]()
+			Close(s)
+		}
+	}
+	return true
+}
+
+func (r *raceStream[T]) Recv() (T, error) {
+	return r.RecvContext(context.Background())
+}
+
+func (r *raceStream[T]) RecvContext(ctx context.Context) (T, error) {
+	var zero T
+	r.mu.Lock()
+	winner, err := r.winner, r.err
+	r.mu.Unlock()
+	if winner != nil {
+		return RecvContext(ctx, winner)
+	}
+	if err != nil {
+		return zero, err
+	}
+	if !r.started {
+		r.started = true
+		r.results = make(chan raceResult[T], r.maxAttempts)
+		for r.delay <= 0 && len(r.candidates) < r.maxAttempts && !r.isClosed() {
+			r.launch()
+		}
+	}
+
+	for {
+		if r.isClosed() {
+			return zero, ErrClosed
+		}
+		if r.running == 0 {
+			if len(r.candidates) < r.maxAttempts {
+				r.launch()
+				continue
+			}
+			err := error(io.EOF)
+			if r.lastErr != nil {
+				err = r.lastErr
+			}
+			r.mu.Lock()
+			if r.err == nil {
+				r.err = err
+			}
+			err = r.err
+			r.mu.Unlock()
+			return zero, err
+		}
+
+		var timeout <-chan time.Time
+		if r.timer != nil && len(r.candidates) < r.maxAttempts { // timer只在Recv中创建
+			timeout = r.timer.C
+		}
+		select {
+		case res := <-r.results:
+			r.running--
+			if res.err == nil {
+				if !r.commit(res.attempt) {
+					return zero, ErrClosed
+				}
+				return res.val, nil
+			}
+			r.recordErr(res.err)
+			if r.delay > 0 && len(r.candidates) < r.maxAttempts {
+				r.launch() // 首包之前就失败了，不用等delay
+			}
+		case <-timeout:
+			r.launch()
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-r.closed:
+			return zero, ErrClosed
+		}
+	}
+}
+
+// Close 关闭所有候选流，之后才创建的候选流也会被关闭
+func (r *raceStream[T]) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.isClosed() {
+		close(r.closed)
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	if r.err == nil {
+		r.err = ErrClosed
+	}
+	if r.winner != nil {
+		return Close(r.winner)
+	}
+	if r.all != nil {
+		return closeAll(r.all)
+	}
+	return closeAll(r.candidates)
+}
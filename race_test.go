@@ -0,0 +1,137 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRace(t *testing.T) {
+	t.Run("fastest first token wins", func(t *testing.T) {
+		slow, slowClosed := trackClose(delayedStream(40*time.Millisecond, "slow1", "slow2"))
+		fast, fastClosed := trackClose(delayedStream(10*time.Millisecond, "fast1", "fast2"))
+		s := Race(slow, fast)
+		expectStream(t, s, []string{"fast1", "fast2"}, io.EOF)
+		if slowClosed.Load() != 1 || fastClosed.Load() != 0 {
+			t.Fatal("only the loser should be closed")
+		}
+	})
+
+	t.Run("empty and failed candidates are skipped", func(t *testing.T) {
+		boom := errors.New("boom")
+		s := Race(Empty[string](), FromErr[string](boom), delayedStream(10*time.Millisecond, "ok"))
+		expectStream(t, s, []string{"ok"}, io.EOF)
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		boom := errors.New("boom")
+		expectStream(t, Race(Empty[string](), FromErr[string](boom)), []string{}, boom)
+		expectStream(t, Race(Empty[string](), nil), []string{}, io.EOF)
+		expectStream(t, Race[string](), []string{}, io.EOF)
+	})
+
+	t.Run("close before start", func(t *testing.T) {
+		a, closed := trackClose(FromSlice([]int{1}))
+		Close(Race(a))
+		if closed.Load() != 1 {
+			t.Fatal("candidate was not closed")
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		s := Race(blockingStream[int](), blockingStream[int]())
+		expectCanceledPromptly(t, s)
+		Close(s)
+	})
+}
+
+func TestHedge(t *testing.T) {
+	t.Run("primary answers in time", func(t *testing.T) {
+		var attempts atomic.Int32
+		s := Hedge(func(attempt int) (Stream[string], error) {
+			attempts.Add(1)
+			return delayedStream(5*time.Millisecond, "a", "b"), nil
+		}, 50*time.Millisecond, 3)
+		expectStream(t, s, []string{"a", "b"}, io.EOF)
+		if attempts.Load() != 1 {
+			t.Fatalf("started %d attempts, want 1", attempts.Load())
+		}
+	})
+
+	t.Run("backup wins after delay", func(t *testing.T) {
+		var closed atomic.Int32
+		s := Hedge(func(attempt int) (Stream[string], error) {
+			if attempt == 0 {
+				s, c := trackClose(blockingStream[string]())
+				go func() {
+					for c.Load() == 0 {
+						time.Sleep(time.Millisecond)
+					}
+					closed.Store(1)
+				}()
+				return s, nil
+			}
+			return FromSlice([]string{"backup"}), nil
+		}, 10*time.Millisecond, 2)
+		start := time.Now()
+		expectStream(t, s, []string{"backup"}, io.EOF)
+		if cost := time.Since(start); cost < 10*time.Millisecond {
+			t.Fatalf("backup started too early: %s", cost)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if closed.Load() != 1 {
+			t.Fatal("slow primary was not closed")
+		}
+	})
+
+	t.Run("failure starts backup immediately", func(t *testing.T) {
+		boom := errors.New("boom")
+		s := Hedge(func(attempt int) (Stream[string], error) {
+			if attempt == 0 {
+				return nil, boom
+			}
+			return FromSlice([]string{"ok"}), nil
+		}, time.Hour, 2)
+		expectStream(t, s, []string{"ok"}, io.EOF)
+	})
+
+	t.Run("all attempts fail", func(t *testing.T) {
+		boom := errors.New("boom")
+		s := Hedge(func(attempt int) (Stream[string], error) {
+			return FromErr[string](boom), nil
+		}, time.Millisecond, 3)
+		expectStream(t, s, []string{}, boom)
+	})
+
+	t.Run("close while recv blocked", func(t *testing.T) {
+		var mu sync.Mutex
+		var closed []*atomic.Int32
+		s := Hedge(func(attempt int) (Stream[string], error) {
+			s, c := trackClose(blockingStream[string]())
+			mu.Lock()
+			closed = append(closed, c)
+			mu.Unlock()
+			return s, nil
+		}, time.Hour, 3)
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := s.Recv()
+			errCh <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		Close(s)
+		if err := <-errCh; !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for i, c := range closed {
+			if c.Load() == 0 {
+				t.Fatalf("attempt %d was not closed", i)
+			}
+		}
+	})
+}
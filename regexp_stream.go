@@ -0,0 +1,207 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"unicode/utf8"
+)
+
+// checkRegexp 检查正则是否可以用于流式匹配，和labels一样是写在代码里的，不合法时panic
+func checkRegexp(re *regexp.Regexp, maxLookahead int) {
+	if maxLookahead <= 0 {
+		panic(fmt.Sprintf("streams: maxLookahead of %q must be positive", re))
+	}
+	if re.MatchString("") {
+		panic(fmt.Sprintf("streams: regexp %q matches empty string", re))
+	}
+}
+
+// findRegexp 在buf[from:]中查找re最左边的匹配，返回确定的匹配；没有确定的匹配时返回buf中可以安全输出的长度
+// 匹配的长度不超过maxLookahead，所以只要匹配开始之后已经有maxLookahead个字节，这个匹配就不会再变长，前面也不会再出现新的匹配
+// flush为true时表示不会再有新数据了
+func findRegexp(re *regexp.Regexp, buf string, from, maxLookahead int, flush bool) (safe int, loc []int, found bool) {
+	loc = re.FindStringSubmatchIndex(buf[from:])
+	if loc != nil && loc[0] == loc[1] { // 空匹配(比如\b)没有意义，当作没有匹配
+		loc = nil
+	}
+	for i := range loc {
+		if loc[i] >= 0 {
+			loc[i] += from
+		}
+	}
+	if loc != nil && (flush || len(buf) >= loc[0]+maxLookahead) {
+		return loc[0], loc, true
+	}
+	if flush {
+		return len(buf), nil, false
+	}
+
+	safe = max(len(buf)-maxLookahead, from)
+	for safe > from && safe < len(buf) && !utf8.RuneStart(buf[safe]) {
+		safe--
+	}
+	if loc != nil {
+		safe = min(safe, loc[0])
+	}
+	return safe, nil, false
+}
+
+// ReadUntilRegexp 合并流中的字符串直到遇到re的匹配，返回的字符串包含匹配的部分
+// 注意事项：
+// 1. maxLookahead是匹配的最大长度(字节)，匹配开始之后要再收到maxLookahead个字节才能确定这个匹配，上游结束时直接确定
+// 2. re不能匹配空字符串；^、$、\b等断言只能看到还没有返回的数据，不建议使用
+// 3. 超过maxLookahead的匹配会被截断，和ReadUntil一样，WithMaxHoldback超时时直接返回已经读到的数据
+func (b *StringReader) ReadUntilRegexp(re *regexp.Regexp, maxLookahead int) (string, error) {
+	return b.ReadUntilRegexpContext(context.Background(), re, maxLookahead)
+}
+
+// ReadUntilRegexpContext 同ReadUntilRegexp，ctx取消时返回ctx.Err()，已读取的数据保留在buffer中
+func (b *StringReader) ReadUntilRegexpContext(ctx context.Context, re *regexp.Regexp, maxLookahead int) (string, error) {
+	checkRegexp(re, maxLookahead)
+	if b.scanner.matcher == nil {
+		b.scanner.reset(nil)
+	}
+	from := 0 // 之前的数据不可能是匹配的开始
+	for {
+		safe, loc, found := findRegexp(re, b.scanner.buf, from, maxLookahead, false)
+		if found {
+			return b.scanner.cut(loc[1]), nil
+		}
+		from = safe
+
+		v, err := b.src.recv(ctx, len(b.scanner.buf) > 0)
+		if err == errHoldbackExpired {
+			return b.scanner.cut(len(b.scanner.buf)), nil
+		} else if err == io.EOF {
+			if len(b.scanner.buf) == 0 {
+				return "", io.EOF
+			}
+			if _, loc, found := findRegexp(re, b.scanner.buf, from, maxLookahead, true); found {
+				return b.scanner.cut(loc[1]), nil
+			}
+			return b.scanner.cut(len(b.scanner.buf)), nil
+		} else if err != nil {
+			return "", err
+		}
+		b.scanner.write(v)
+	}
+}
+
+type regexpSplitReader struct {
+	src          *StringReader
+	re           *regexp.Regexp
+	maxLookahead int
+}
+
+// SplitRegexp 按re的匹配切分流，每个输出的字符串以匹配结尾(最后一个可能没有)，规则同ReadUntilRegexp
+func SplitRegexp(src Stream[string], re *regexp.Regexp, maxLookahead int, opts ...ParserOption) Stream[string] {
+	checkRegexp(re, maxLookahead)
+	return regexpSplitReader{src: NewStringReader(src, opts...), re: re, maxLookahead: maxLookahead}
+}
+
+func (r regexpSplitReader) Recv() (string, error) {
+	return r.src.ReadUntilRegexp(r.re, r.maxLookahead)
+}
+
+func (r regexpSplitReader) RecvContext(ctx context.Context) (string, error) {
+	return r.src.ReadUntilRegexpContext(ctx, r.re, r.maxLookahead)
+}
+
+func (r regexpSplitReader) Close() error {
+	return r.src.Close()
+}
+
+// RegexpChunk ExtractRegexp的输出，Matched为true时Text是一个完整的匹配
+type RegexpChunk struct {
+	Text    string
+	Matched bool
+	Groups  []string // 子匹配，Groups[0]是整个匹配，没有参与匹配的分组是""
+}
+
+type regexpExtractStream struct {
+	src          holdbackReceiver
+	re           *regexp.Regexp
+	maxLookahead int
+
+	buf     string
+	pending *RegexpChunk // 匹配之前的文本输出之后，再输出的匹配
+	expired bool         // 暂存超时，当前buffer按上游结束处理
+	eof     bool
+}
+
+// ExtractRegexp 增量地在流中查找re的匹配，匹配和匹配之间的文本分别输出，比如用\[\d+\]提取引用标记
+// 只有可能是匹配开始的末尾maxLookahead个字节会被暂存，其余规则同ReadUntilRegexp，WithMaxHoldback超时时会把暂存的数据当作流已经结束处理
+func ExtractRegexp(src Stream[string], re *regexp.Regexp, maxLookahead int, opts ...ParserOption) Stream[RegexpChunk] {
+	checkRegexp(re, maxLookahead)
+	return &regexpExtractStream{
+		src:          newHoldbackReceiver(src, opts),
+		re:           re,
+		maxLookahead: maxLookahead,
+	}
+}
+
+func (s *regexpExtractStream) cut(n int) string {
+	text := s.buf[:n]
+	s.buf = s.buf[n:]
+	return text
+}
+
+// cutOverflowBuffer 处理buffer中已经可以确定的数据，ok为false表示需要更多的上游数据
+func (s *regexpExtractStream) cutOverflowBuffer() (c RegexpChunk, ok bool) {
+	if s.pending != nil {
+		c, s.pending = *s.pending, nil
+		return c, true
+	}
+	safe, loc, found := findRegexp(s.re, s.buf, 0, s.maxLookahead, s.eof || s.expired)
+	if !found {
+		text := s.cut(safe)
+		return RegexpChunk{Text: text}, text != ""
+	}
+	groups := make([]string, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = s.buf[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	before := s.cut(loc[0])
+	match := RegexpChunk{Text: s.cut(loc[1] - loc[0]), Matched: true, Groups: groups}
+	if before == "" {
+		return match, true
+	}
+	s.pending = &match
+	return RegexpChunk{Text: before}, true
+}
+
+func (s *regexpExtractStream) Recv() (RegexpChunk, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *regexpExtractStream) RecvContext(ctx context.Context) (RegexpChunk, error) {
+	for {
+		if c, ok := s.cutOverflowBuffer(); ok {
+			return c, nil
+		}
+		if s.eof {
+			return RegexpChunk{}, io.EOF
+		}
+
+		s.expired = false
+		chunk, err := s.src.recv(ctx, s.buf != "")
+		if err == errHoldbackExpired {
+			s.expired = true
+			continue
+		} else if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return RegexpChunk{}, err
+		}
+		s.buf += chunk
+	}
+}
+
+func (s *regexpExtractStream) Close() error {
+	return s.src.close()
+}
@@ -0,0 +1,113 @@
+package streams
+
+import (
+	"io"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSplitRegexp(t *testing.T) {
+	list := regexp.MustCompile(`\n\d+\. `)
+	tests := []struct {
+		name  string
+		input []string
+		re    *regexp.Regexp
+		want  []string
+	}{
+		{"across chunks", []string{"steps:\n1", ". a\n", "2. b\n3", "", ". c"}, list, []string{"steps:\n1. ", "a\n2. ", "b\n3. ", "c"}},
+		{"no match", []string{"a", "b\n1", "x"}, list, []string{"ab\n1x"}},
+		{"match at eof", []string{"a\n12. "}, list, []string{"a\n12. "}},
+		{"leftmost wins", []string{"x[1][2", "]y"}, regexp.MustCompile(`\[\d+\]`), []string{"x[1]", "[2]", "y"}},
+		{"multibyte", []string{"你好[", "1]世界"}, regexp.MustCompile(`\[\d+\]`), []string{"你好[1]", "世界"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStream(t, SplitRegexp(FromSlice(tt.input), tt.re, 8), tt.want, io.EOF)
+		})
+	}
+
+	t.Run("lookahead truncates long match", func(t *testing.T) {
+		s := SplitRegexp(FromSlice([]string{"a[123456]b"}), regexp.MustCompile(`\[\d+\]`), 4)
+		expectStream(t, s, []string{"a[123456]", "b"}, io.EOF)
+	})
+
+	t.Run("ReadUntilRegexp mixed with ReadUntil", func(t *testing.T) {
+		r := NewStringReader(FromSlice([]string{"a[1", "]b,c"}))
+		if v, err := r.ReadUntilRegexp(regexp.MustCompile(`\[\d\]`), 3); v != "a[1]" || err != nil {
+			t.Fatalf("ReadUntilRegexp() = %q, %v", v, err)
+		}
+		if v, err := r.ReadUntil([]string{","}); v != "b," || err != nil {
+			t.Fatalf("ReadUntil() = %q, %v", v, err)
+		}
+		expectStringStream(t, r, "c", io.EOF)
+	})
+
+	t.Run("max holdback", func(t *testing.T) {
+		s := SplitRegexp(slowStream("a\n1", 100*time.Millisecond, ". b"), list, 8, WithMaxHoldback(10*time.Millisecond))
+		expectStream(t, s, []string{"a\n1", ". b"}, io.EOF)
+	})
+
+	t.Run("rejects empty match", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		SplitRegexp(FromSlice([]string{"a"}), regexp.MustCompile(`\d*`), 8)
+	})
+}
+
+func TestExtractRegexp(t *testing.T) {
+	cite := regexp.MustCompile(`\[(\d+)\]`)
+	// collect 收集所有输出，相邻的文本合并在一起，文本切分的位置取决于maxLookahead
+	collect := func(t *testing.T, s Stream[RegexpChunk]) []RegexpChunk {
+		t.Helper()
+		var got []RegexpChunk
+		for {
+			c, err := s.Recv()
+			if err == io.EOF {
+				return got
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(got); n > 0 && !c.Matched && !got[n-1].Matched {
+				got[n-1].Text += c.Text
+				continue
+			}
+			got = append(got, c)
+		}
+	}
+
+	t.Run("citations across chunks", func(t *testing.T) {
+		s := ExtractRegexp(FromSlice([]string{"see [1", "2] and [3]", "."}), cite, 6)
+		want := []RegexpChunk{
+			{Text: "see "},
+			{Text: "[12]", Matched: true, Groups: []string{"[12]", "12"}},
+			{Text: " and "},
+			{Text: "[3]", Matched: true, Groups: []string{"[3]", "3"}},
+			{Text: "."},
+		}
+		if got := collect(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("streams text outside lookahead", func(t *testing.T) {
+		s := ExtractRegexp(blockingStream("hello world [", "1]"), cite, 4)
+		c, err := s.Recv()
+		if err != nil || c.Text != "hello wor" || c.Matched {
+			t.Fatalf("Recv() = %+v, %v", c, err)
+		}
+	})
+
+	t.Run("max holdback", func(t *testing.T) {
+		s := ExtractRegexp(slowStream("a[1", 100*time.Millisecond, "]"), cite, 6, WithMaxHoldback(10*time.Millisecond))
+		want := []RegexpChunk{{Text: "a[1]"}}
+		if got := collect(t, s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package streams
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+type replaceTokensStream struct {
+	src     holdbackReceiver
+	replace func(token string) string
+	rescan  bool
+
+	scanner tokenScanner
+	spans   []rescanSpan // buffer中来自替换结果的区间，按位置排列
+	eof     bool
+	err     error
+}
+
+// rescanSpan buffer中[start, end)是替换结果，depth是经过了几次替换，超过token的个数说明替换形成了循环
+type rescanSpan struct {
+	start, end int
+	depth      int
+}
+
+type ReplaceOption func(*replaceOptions)
+
+type replaceOptions struct {
+	rescan     bool
+	parserOpts []ParserOption
+}
+
+// WithRescanReplacement 替换后的文本会和后面的文本一起重新匹配
+// 注意：替换后的文本包含被替换的token，或者多个token互相替换形成循环时，会返回错误
+func WithRescanReplacement() ReplaceOption {
+	return func(o *replaceOptions) {
+		o.rescan = true
+	}
+}
+
+// WithReplaceParserOptions 传给底层解析器的选项，比如WithMaxHoldback
+func WithReplaceParserOptions(opts ...ParserOption) ReplaceOption {
+	return func(o *replaceOptions) {
+		o.parserOpts = append(o.parserOpts, opts...)
+	}
+}
+
+// ReplaceTokens 把流中的token替换为replacements中对应的文本，token可以跨chunk
+// 多个token从同一位置开始时，更长的token优先，其余规则同NewRemoveTokensStream
+func ReplaceTokens(src Stream[string], replacements map[string]string, opts ...ReplaceOption) Stream[string] {
+	tokens := slices.Collect(maps.Keys(replacements))
+	slices.SortFunc(tokens, func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	return ReplaceTokensFunc(src, tokens, func(token string) string { return replacements[token] }, opts...)
+}
+
+// ReplaceTokensFunc 同ReplaceTokens，replace根据命中的token返回替换后的文本
+// 多个token从同一位置开始时，tokens中排在前面的优先
+// 注意事项:
+//  1. 如果token之间有重叠，取最先遇到的token
+//  2. 默认替换后的文本不会再次匹配，需要时使用WithRescanReplacement
+//  3. 只有末尾可能是某个token前缀的文本才会被暂存，可以通过WithReplaceParserOptions(WithMaxHoldback(d))限制暂存的时间
+func ReplaceTokensFunc(src Stream[string], tokens []string, replace func(token string) string, opts ...ReplaceOption) Stream[string] {
+	tokens = slices.DeleteFunc(slices.Clone(tokens), func(token string) bool { return token == "" })
+	if len(tokens) == 0 {
+		return src
+	}
+	var o replaceOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return newReplaceTokensStream(src, tokens, replace, o)
+}
+
+func newReplaceTokensStream(src Stream[string], tokens []string, replace func(token string) string, o replaceOptions) *replaceTokensStream {
+	return &replaceTokensStream{
+		src:     newHoldbackReceiver(src, o.parserOpts),
+		replace: replace,
+		rescan:  o.rescan,
+		scanner: newTokenScanner(tokens),
+	}
+}
+
+// cut 取出buffer的前n个字节，同时更新spans
+func (s *replaceTokensStream) cut(n int) string {
+	spans := s.spans[:0]
+	for _, sp := range s.spans {
+		if sp.end > n {
+			spans = append(spans, rescanSpan{start: max(sp.start-n, 0), end: sp.end - n, depth: sp.depth})
+		}
+	}
+	s.spans = spans
+	return s.scanner.cut(n)
+}
+
+// unread 把替换结果放回buffer的开头，depth是替换结果经过的替换次数
+func (s *replaceTokensStream) unread(replacement string, depth int) {
+	s.scanner.unread(replacement)
+	n := len(replacement)
+	for i := range s.spans {
+		s.spans[i].start += n
+		s.spans[i].end += n
+	}
+	if n > 0 {
+		s.spans = slices.Insert(s.spans, 0, rescanSpan{end: n, depth: depth})
+	}
+}
+
+// depthOf 返回buffer中[start, end)经过的替换次数，跨越多个区间时取最大的
+// 包含上游原始文本时返回0，因为原始文本被消费了，即使token再次出现也不是循环
+func (s *replaceTokensStream) depthOf(start, end int) int {
+	depth, covered := 0, 0
+	for _, sp := range s.spans {
+		if sp.start < end && start < sp.end {
+			depth = max(depth, sp.depth)
+			covered += min(sp.end, end) - max(sp.start, start)
+		}
+	}
+	if covered < end-start {
+		return 0
+	}
+	return depth
+}
+
+func (s *replaceTokensStream) cutOverflowBuffer() string {
+	for {
+		safe, match, found := s.scanner.scan(s.eof)
+		if !found {
+			return s.cut(safe)
+		}
+		depth := s.depthOf(match.start, match.end) + 1
+		chunk := s.cut(match.start)
+		token := s.cut(match.end - match.start)
+		replacement := s.replace(token)
+		if !s.rescan {
+			if chunk += replacement; chunk != "" {
+				return chunk
+			}
+			continue
+		}
+		if strings.Contains(replacement, token) {
+			s.err = fmt.Errorf("streams: replacement %q contains token %q", replacement, token)
+			return chunk
+		}
+		if depth > len(s.scanner.matcher.tokens) {
+			s.err = fmt.Errorf("streams: replacements form a cycle at token %q", token)
+			return chunk
+		}
+		s.unread(replacement, depth)
+		if chunk != "" {
+			return chunk
+		}
+	}
+}
+
+func (s *replaceTokensStream) Close() error {
+	return s.src.close()
+}
+
+func (s *replaceTokensStream) Recv() (string, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *replaceTokensStream) RecvContext(ctx context.Context) (string, error) {
+	for {
+		if s.err != nil {
+			return "", s.err
+		}
+		chunk := s.cutOverflowBuffer()
+		if chunk != "" {
+			return chunk, nil
+		}
+		if s.err != nil {
+			continue
+		}
+		if s.eof { // 上游已经读完了，buffer也处理完了
+			return "", io.EOF
+		}
+
+		upChunk, err := s.src.recv(ctx, len(s.scanner.buf) > 0)
+		if err == errHoldbackExpired { // 暂存超时，当作普通文本输出
+			return s.cut(len(s.scanner.buf)), nil
+		} else if err == io.EOF { // 上游已经读完了，但是buffer中还有数据，需要处理
+			s.eof = true
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		s.scanner.write(upChunk)
+	}
+}
@@ -0,0 +1,84 @@
+package streams
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplaceTokens(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		src := FromSlice(strings.Split("a<|br|>b gpt-x c", ""))
+		s := ReplaceTokens(src, map[string]string{"<|br|>": "\n", "gpt-x": "***"})
+		expectStringStream(t, s, "a\nb *** c", io.EOF)
+	})
+
+	t.Run("longer token wins at same start", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"[^3]", " [^"}), map[string]string{"[^": "?", "[^3]": "<3>"})
+		expectStringStream(t, s, "<3> ?", io.EOF)
+	})
+
+	t.Run("func", func(t *testing.T) {
+		src := FromSlice([]string{"see [^1", "] and [^2]"})
+		s := ReplaceTokensFunc(src, []string{"[^1]", "[^2]"}, func(token string) string {
+			return "(" + token[2:3] + ")"
+		})
+		expectStringStream(t, s, "see (1) and (2)", io.EOF)
+	})
+
+	t.Run("no rescan by default", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"xab"}), map[string]string{"x": "a", "aab": "!"})
+		expectStringStream(t, s, "aab", io.EOF)
+	})
+
+	t.Run("max holdback", func(t *testing.T) {
+		s := ReplaceTokens(slowStream("a<|b", 100*time.Millisecond, "r|>"), map[string]string{"<|br|>": "\n"}, WithReplaceParserOptions(WithMaxHoldback(10*time.Millisecond)))
+		expectStream(t, s, []string{"a", "<|b", "r|>"}, io.EOF)
+	})
+
+	t.Run("rescan", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"xab"}), map[string]string{"x": "a", "aab": "!"}, WithRescanReplacement())
+		expectStringStream(t, s, "!", io.EOF)
+	})
+
+	t.Run("rescan cycle", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"xA", "y"}), map[string]string{"A": "B", "B": "A"}, WithRescanReplacement())
+		done := make(chan error, 1)
+		go func() {
+			_, err := CollectString(s)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("replacement cycle is not detected")
+		}
+	})
+
+	t.Run("rescan chain without cycle", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"A", "B"}), map[string]string{"A": "B", "B": "C", "C": "D"}, WithRescanReplacement())
+		expectStringStream(t, s, "DD", io.EOF)
+	})
+
+	t.Run("rescan repeated token in one replacement", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"A"}), map[string]string{"A": "BB", "B": "c"}, WithRescanReplacement())
+		expectStringStream(t, s, "cc", io.EOF)
+	})
+
+	t.Run("rescan token spanning replacement and text", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"xyx", "y"}), map[string]string{"x": "a", "ay": "x"}, WithRescanReplacement())
+		expectStringStream(t, s, "aa", io.EOF)
+	})
+
+	t.Run("rescan replacement contains token", func(t *testing.T) {
+		s := ReplaceTokens(FromSlice([]string{"1a2"}), map[string]string{"a": "aa"}, WithRescanReplacement())
+		_, err := CollectString(s)
+		if err == nil {
+			t.Fatalf("expected error, got %v", err)
+		}
+	})
+}
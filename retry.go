@@ -0,0 +1,170 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// RetryPolicy 描述RetryStream什么时候重试、重试多少次、每次重试前等待多久
+type RetryPolicy struct {
+	MaxAttempts    int                  // 最多创建多少次上游(含首次)，<=0表示不限制
+	MaxElapsed     time.Duration        // 从首次创建上游开始，超过这个时间就不再重试，<=0表示不限制
+	InitialBackoff time.Duration        // 第一次重试前等待的时间
+	MaxBackoff     time.Duration        // 等待时间的上限，<=0表示不限制
+	Multiplier     float64              // 每次重试的等待时间是上一次的多少倍，<=0时按2处理
+	Retryable      func(err error) bool // 判断上游的错误是否可以重试，nil表示除了ErrClosed之外的错误都重试
+	FactoryResumes bool                 // true: factory根据delivered自行从断点续传；false: 新的上游会从头输出，RetryStream会跳过前delivered个数据
+}
+
+// retryable 消费方ctx的错误在RecvContext中已经直接返回了，这里只判断上游自己的错误，上游的context.DeadlineExceeded之类的超时也可以重试
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return !errors.Is(err, ErrClosed)
+	}
+	return p.Retryable(err)
+}
+
+// backoff 第retries次重试前需要等待的时间，retries从1开始
+func (p RetryPolicy) backoff(retries int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retries; i++ {
+		d *= multiplier
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+	return time.Duration(d)
+}
+
+type retryStream[T any] struct {
+	factory func(attempt int, delivered int) (Stream[T], error)
+	policy  RetryPolicy
+
+	cur       Stream[T]
+	attempts  int
+	delivered int       // 已经输出给下游的数据个数
+	skip      int       // 新上游还需要跳过的数据个数
+	startAt   time.Time // 首次创建上游的时间
+	retryAt   time.Time // 下次创建上游的时间，等待期间被ctx打断时，下次Recv继续等待
+	err       error
+}
+
+// RetryStream 上游中途出错时，按policy重新创建上游并接着输出，下游看到的是一条连续的流
+// factory的attempt从0开始，delivered是已经输出给下游的数据个数，可以用来实现断点续传(见RetryPolicy.FactoryResumes)
+// 不可重试的错误、重试次数或时间用尽时，返回最后一次的错误
+func RetryStream[T any](factory func(attempt int, delivered int) (Stream[T], error), policy RetryPolicy) Stream[T] {
+	return &retryStream[T]{
+		factory: factory,
+		policy:  policy,
+	}
+}
+
+func (r *retryStream[T]) Recv() (T, error) {
+	return r.RecvContext(context.Background())
+}
+
+func (r *retryStream[T]) RecvContext(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		if r.err != nil {
+			return zero, r.err
+		}
+		if r.cur == nil {
+			if err := r.open(ctx); err != nil {
+				if isContextErr(ctx, err) {
+					return zero, err
+				}
+				if err := r.fail(err); err != nil {
+					return zero, err
+				}
+				continue
+			}
+		}
+
+		v, err := RecvContext(ctx, r.cur)
+		if isContextErr(ctx, err) {
+			return zero, err
+		}
+		if err == io.EOF {
+			r.err = err
+			return zero, err
+		}
+		if err != nil {
+			Close(r.cur)
+			r.cur = nil
+			if err := r.fail(err); err != nil {
+				return zero, err
+			}
+			continue
+		}
+		if r.skip > 0 { // 新上游从头开始输出，跳过下游已经收到的部分
+			r.skip--
+			continue
+		}
+		r.delivered++
+		return v, nil
+	}
+}
+
+// open 等到重试时间之后创建上游
+func (r *retryStream[T]) open(ctx context.Context) error {
+	if wait := time.Until(r.retryAt); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if r.attempts == 0 {
+		r.startAt = time.Now()
+	}
+	attempt := r.attempts
+	r.attempts++
+	s, err := r.factory(attempt, r.delivered)
+	if err != nil {
+		return err
+	}
+	r.cur = avoidNil(s)
+	r.skip = 0
+	if !r.policy.FactoryResumes {
+		r.skip = r.delivered
+	}
+	return nil
+}
+
+// fail 判断能否重试，可以则安排下次重试的时间并返回nil，否则记录并返回最终的错误
+func (r *retryStream[T]) fail(err error) error {
+	p := r.policy
+	if !p.retryable(err) || (p.MaxAttempts > 0 && r.attempts >= p.MaxAttempts) {
+		r.err = err
+		return err
+	}
+	backoff := p.backoff(r.attempts)
+	if p.MaxElapsed > 0 && time.Since(r.startAt)+backoff > p.MaxElapsed {
+		r.err = err
+		return err
+	}
+	r.retryAt = time.Now().Add(backoff)
+	return nil
+}
+
+func (r *retryStream[T]) Close() error {
+	if r.err == nil {
+		r.err = ErrClosed
+	}
+	if r.cur != nil {
+		return Close(r.cur)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRetryStream(t *testing.T) {
+	boom := errors.New("boom")
+	// flaky 第attempt次创建的上游输出前failAt个数据之后出错
+	flaky := func(items []string, failAt ...int) func(attempt, delivered int) (Stream[string], error) {
+		return func(attempt, delivered int) (Stream[string], error) {
+			if attempt < len(failAt) {
+				return Concat(FromSlice(items[:failAt[attempt]]), FromErr[string](boom)), nil
+			}
+			return FromSlice(items), nil
+		}
+	}
+	items := []string{"a", "b", "c", "d"}
+
+	t.Run("skip delivered items", func(t *testing.T) {
+		s := RetryStream(flaky(items, 2, 1, 3), RetryPolicy{MaxAttempts: 4})
+		expectStream(t, s, items, io.EOF)
+	})
+
+	t.Run("factory resumes", func(t *testing.T) {
+		var gotDelivered []int
+		s := RetryStream(func(attempt, delivered int) (Stream[string], error) {
+			gotDelivered = append(gotDelivered, delivered)
+			if attempt == 0 {
+				return Concat(FromSlice(items[:3]), FromErr[string](boom)), nil
+			}
+			return FromSlice(items[delivered:]), nil
+		}, RetryPolicy{FactoryResumes: true})
+		expectStream(t, s, items, io.EOF)
+		if len(gotDelivered) != 2 || gotDelivered[1] != 3 {
+			t.Fatalf("delivered passed to factory = %v, want [0 3]", gotDelivered)
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		s := RetryStream(flaky(items, 1, 1, 1), RetryPolicy{MaxAttempts: 3})
+		expectStream(t, s, []string{"a"}, boom)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		s := RetryStream(flaky(items, 2), RetryPolicy{Retryable: func(err error) bool { return err != boom }})
+		expectStream(t, s, []string{"a", "b"}, boom)
+	})
+
+	t.Run("upstream context errors are retryable", func(t *testing.T) {
+		s := RetryStream(func(attempt, delivered int) (Stream[string], error) {
+			if attempt == 0 {
+				return Concat(FromSlice(items[:1]), FromErr[string](context.DeadlineExceeded)), nil
+			}
+			return FromSlice(items), nil
+		}, RetryPolicy{})
+		expectStream(t, s, items, io.EOF)
+
+		var got error
+		s = RetryStream(func(attempt, delivered int) (Stream[string], error) {
+			return FromErr[string](context.Canceled), nil
+		}, RetryPolicy{Retryable: func(err error) bool { got = err; return false }})
+		expectStream(t, s, []string{}, context.Canceled)
+		if got != context.Canceled {
+			t.Fatalf("Retryable got %v, want context.Canceled", got)
+		}
+	})
+
+	t.Run("factory error is retried", func(t *testing.T) {
+		s := RetryStream(func(attempt, delivered int) (Stream[string], error) {
+			if attempt == 0 {
+				return nil, boom
+			}
+			return FromSlice(items), nil
+		}, RetryPolicy{})
+		expectStream(t, s, items, io.EOF)
+	})
+
+	t.Run("backoff and max elapsed", func(t *testing.T) {
+		attempts := 0
+		factory := flaky(items, 0, 0, 0, 0, 0)
+		start := time.Now()
+		s := RetryStream(func(attempt, delivered int) (Stream[string], error) {
+			attempts++
+			return factory(attempt, delivered)
+		}, RetryPolicy{
+			InitialBackoff: 20 * time.Millisecond,
+			MaxElapsed:     100 * time.Millisecond,
+		})
+		// 等待20ms、40ms之后，第三次重试需要等80ms，会超过100ms的上限
+		expectStream(t, s, []string{}, boom)
+		if attempts != 3 {
+			t.Fatalf("created %d upstreams, want 3", attempts)
+		}
+		if cost := time.Since(start); cost < 60*time.Millisecond {
+			t.Fatalf("gave up after %s, want at least 60ms", cost)
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2}
+		for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+			if got := p.backoff(i + 1); got != want {
+				t.Errorf("backoff(%d) = %s, want %s", i+1, got, want)
+			}
+		}
+	})
+
+	t.Run("context during backoff", func(t *testing.T) {
+		s := RetryStream(flaky(items, 0), RetryPolicy{InitialBackoff: time.Hour})
+		expectCanceledPromptly(t, s)
+	})
+}
@@ -1,6 +1,9 @@
 package streams
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 type SafeStream[T any] struct {
 	Stream[T]
@@ -20,3 +23,14 @@ func (s *SafeStream[T]) Recv() (T, error) {
 	defer s.mu.Unlock()
 	return s.Stream.Recv()
 }
+
+// Close 不加锁，以便在其他协程阻塞在Recv时也能关闭上游
+func (s *SafeStream[T]) Close() error {
+	return Close(s.Stream)
+}
+
+func (s *SafeStream[T]) RecvContext(ctx context.Context) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return RecvContext(ctx, s.Stream)
+}
@@ -0,0 +1,268 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type SentenceOption func(*sentenceOptions)
+
+type sentenceOptions struct {
+	minLen        int
+	maxLen        int
+	abbreviations map[string]bool
+	parserOpts    []ParserOption
+}
+
+// defaultAbbreviations 后面的.不是句子结尾的英文缩写，小写
+var defaultAbbreviations = []string{
+	"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "vs", "etc", "e.g", "i.e",
+	"inc", "ltd", "co", "no", "fig", "approx", "dept", "vol",
+}
+
+// WithSentenceLength 限制句子的长度(字符数)，0表示不限制
+// 短于minLen的句子会和下一句合并；超过maxLen还没有结束的句子会在最后一个逗号、顿号、冒号、分号或空白之后切开，没有的话直接在maxLen处切开
+func WithSentenceLength(minLen, maxLen int) SentenceOption {
+	return func(o *sentenceOptions) {
+		o.minLen, o.maxLen = minLen, maxLen
+	}
+}
+
+// WithSentenceAbbreviations 替换默认的英文缩写列表，比如Mr、e.g，缩写后面的.不会被当作句子结尾，大小写不敏感
+func WithSentenceAbbreviations(abbreviations ...string) SentenceOption {
+	return func(o *sentenceOptions) {
+		o.abbreviations = make(map[string]bool, len(abbreviations))
+		for _, a := range abbreviations {
+			o.abbreviations[strings.ToLower(strings.TrimSuffix(a, "."))] = true
+		}
+	}
+}
+
+// WithSentenceParserOptions 传给底层NewStringReader的选项，比如用WithMaxHoldback在上游停顿时直接输出已经读到的数据
+func WithSentenceParserOptions(opts ...ParserOption) SentenceOption {
+	return func(o *sentenceOptions) {
+		o.parserOpts = append(o.parserOpts, opts...)
+	}
+}
+
+func newSentenceOptions(opts []SentenceOption) sentenceOptions {
+	var o sentenceOptions
+	WithSentenceAbbreviations(defaultAbbreviations...)(&o)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SplitSentences 把流切分为句子，适合逐句送给TTS
+// 注意事项：
+// 1. 句子在中文的。！？…、英文的.!?和换行处结束，连续的标点和紧跟着的右引号、右括号属于同一句
+// 2. 英文的标点后面要有空白才算结尾，所以3.14、example.com不会被切开；缩写和姓名缩写(前后紧挨着另一个姓名缩写或者首字母大写的名字的单个大写字母)后面的.也不算结尾
+// 3. 所有输出拼起来和输入完全一致，句子之间的空白留在下一句的开头
+// 4. 结尾的标点后面可能还有右引号，所以要再收到一个字符才能确定；上游结束时剩下的数据作为最后一句输出
+func SplitSentences(src Stream[string], opts ...SentenceOption) Stream[string] {
+	o := newSentenceOptions(opts)
+	return sentenceReader{src: NewStringReader(src, o.parserOpts...), opts: o}
+}
+
+// ToSentenceReader 将ReadUntil按句子切分的版本封装为Stream，规则同SplitSentences，WithSentenceParserOptions不生效
+func (b *StringReader) ToSentenceReader(opts ...SentenceOption) Stream[string] {
+	return sentenceReader{src: b, opts: newSentenceOptions(opts)}
+}
+
+type sentenceReader struct {
+	src  *StringReader
+	opts sentenceOptions
+}
+
+func (r sentenceReader) Recv() (string, error) {
+	return r.RecvContext(context.Background())
+}
+
+func (r sentenceReader) RecvContext(ctx context.Context) (string, error) {
+	b := r.src
+	if b.scanner.matcher == nil {
+		b.scanner.reset(nil)
+	}
+	s := sentenceScanner{opts: &r.opts}
+	for {
+		if n := s.next(b.scanner.buf, false); n > 0 {
+			return b.scanner.cut(n), nil
+		}
+
+		v, err := b.src.recv(ctx, len(b.scanner.buf) > 0)
+		if err == errHoldbackExpired {
+			return b.scanner.cut(len(b.scanner.buf)), nil
+		} else if err == io.EOF {
+			if len(b.scanner.buf) == 0 {
+				return "", io.EOF
+			}
+			return b.scanner.cut(s.next(b.scanner.buf, true)), nil
+		} else if err != nil {
+			return "", err
+		}
+		b.scanner.write(v)
+	}
+}
+
+func (r sentenceReader) Close() error {
+	return r.src.Close()
+}
+
+// sentenceScanner 增量地查找buffer中第一个句子的结尾，buffer只会在末尾追加数据
+type sentenceScanner struct {
+	opts  *sentenceOptions
+	pos   int  // buf[:pos]中没有句子的结尾
+	runes int  // buf[:pos]去掉开头空白之后的字符数
+	soft  int  // buf[:pos]中最后一个可以在超长时切开的位置
+	text  bool // buf[:pos]中是否有空白之外的字符，开头的空白不算句子的长度，也不能在那里切开
+}
+
+// next 返回buf中第一个句子的长度，0表示需要更多的数据，flush为true时表示不会再有新数据了
+func (s *sentenceScanner) next(buf string, flush bool) int {
+	o := s.opts
+	for s.pos < len(buf) {
+		if o.maxLen > 0 && s.runes >= o.maxLen {
+			if s.soft > 0 {
+				return s.soft
+			}
+			return s.pos
+		}
+		r, size := utf8.DecodeRuneInString(buf[s.pos:])
+		if !s.text && unicode.IsSpace(r) {
+			s.pos += size
+			continue
+		}
+		s.text = true
+		end, more := o.sentenceEnd(buf, s.pos, flush)
+		if more {
+			return 0
+		}
+		if end > 0 {
+			s.runes += utf8.RuneCountInString(buf[s.pos:end])
+			s.pos = end
+			if s.runes >= o.minLen || o.maxLen > 0 && s.runes >= o.maxLen {
+				return end
+			}
+			s.soft = end // 太短了，和下一句合并
+			continue
+		}
+		s.pos += size
+		s.runes++
+		if isSoftBreak(r) {
+			s.soft = s.pos
+		}
+	}
+	if flush {
+		return len(buf)
+	}
+	return 0
+}
+
+// sentenceEnd 判断buf[i]开始的标点是不是句子的结尾，是的话返回结尾的位置，more为true表示需要更多的数据才能确定
+func (o *sentenceOptions) sentenceEnd(buf string, i int, flush bool) (end int, more bool) {
+	r, size := utf8.DecodeRuneInString(buf[i:])
+	if r == '\n' {
+		return i + size, false
+	}
+	if !isSentenceTerminal(r) {
+		return 0, false
+	}
+	cjk := r > unicode.MaxASCII
+	j := i + size
+	for j < len(buf) {
+		r, size := utf8.DecodeRuneInString(buf[j:])
+		if isSentenceTerminal(r) {
+			cjk = cjk || r > unicode.MaxASCII
+		} else if !isCloser(r) {
+			break
+		}
+		j += size
+	}
+	if j == len(buf) {
+		return j, !flush
+	}
+	if cjk {
+		return j, false
+	}
+	if next, _ := utf8.DecodeRuneInString(buf[j:]); !unicode.IsSpace(next) {
+		return 0, false
+	}
+	if r == '.' && j == i+size {
+		if o.isAbbreviation(buf[:i]) {
+			return 0, false
+		}
+		if initial, more := isInitial(buf[:i], buf[j:], flush); more || initial {
+			return 0, more
+		}
+	}
+	return j, false
+}
+
+// isAbbreviation 判断.前面的单词是不是缩写
+func (o *sentenceOptions) isAbbreviation(before string) bool {
+	return o.abbreviations[strings.ToLower(lastWord(before))]
+}
+
+// isInitial 判断.前面的单个大写字母是不是姓名缩写，比如J. K. Rowling、John F. Kennedy，而不是I got an A.这样的句子结尾
+// 前一个单词是姓名缩写或者首字母大写的名字，或者后一个单词也是姓名缩写时才算，more为true表示需要更多的数据才能确定
+func isInitial(before, after string, flush bool) (initial bool, more bool) {
+	word := lastWord(before)
+	if r, size := utf8.DecodeRuneInString(word); size != len(word) || !unicode.IsUpper(r) {
+		return false, false
+	}
+	prev := lastWord(strings.TrimRightFunc(before[:len(before)-len(word)], unicode.IsSpace))
+	if isInitialWord(prev) || isCapitalized(prev) {
+		return true, false
+	}
+	next := strings.TrimLeftFunc(after, unicode.IsSpace)
+	r, size := utf8.DecodeRuneInString(next)
+	if len(next) < size+1 {
+		return false, !flush && (next == "" || unicode.IsUpper(r))
+	}
+	return unicode.IsUpper(r) && next[size] == '.', false
+}
+
+// lastWord 返回最后一个空白之后的单词，去掉开头的左引号、左括号
+func lastWord(s string) string {
+	return strings.TrimLeftFunc(s[strings.LastIndexFunc(s, unicode.IsSpace)+1:], isOpener)
+}
+
+// isInitialWord 判断单词是不是X.这样的姓名缩写
+func isInitialWord(word string) bool {
+	r, size := utf8.DecodeRuneInString(word)
+	return unicode.IsUpper(r) && word[size:] == "."
+}
+
+// isCapitalized 判断单词是不是John这样首字母大写、其余小写的名字
+func isCapitalized(word string) bool {
+	r, size := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(r) || size == len(word) {
+		return false
+	}
+	for _, r := range word[size:] {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isSentenceTerminal(r rune) bool {
+	return strings.ContainsRune(".!?。！？…", r)
+}
+
+func isSoftBreak(r rune) bool {
+	return strings.ContainsRune(",;:，、；：", r) || unicode.IsSpace(r)
+}
+
+func isOpener(r rune) bool {
+	return strings.ContainsRune("\"'([{“‘「『（【《", r)
+}
+
+func isCloser(r rune) bool {
+	return strings.ContainsRune("\"')]}”’」』）】》", r)
+}
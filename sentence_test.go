@@ -0,0 +1,57 @@
+package streams
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		opts  []SentenceOption
+		want  []string
+	}{
+		{"cjk", []string{"你好。今天", "天气不错！", "出去走走吗？"}, nil, []string{"你好。", "今天天气不错！", "出去走走吗？"}},
+		{"latin", []string{"Hello world. How", " are you?! Fine"}, nil, []string{"Hello world.", " How are you?!", " Fine"}},
+		{"closing quote in next chunk", []string{"他说：“好的。", "”然后走了。"}, nil, []string{"他说：“好的。”", "然后走了。"}},
+		{"decimal and domain", []string{"Pi is 3.", "14 on example.com. Yes."}, nil, []string{"Pi is 3.14 on example.com.", " Yes."}},
+		{"abbreviations", []string{"Mr. Smith met J. K. Rowling, e.g. at noon. Then left."}, nil, []string{"Mr. Smith met J. K. Rowling, e.g. at noon.", " Then left."}},
+		{"initial ends sentence", []string{"I got an A. Then I left."}, nil, []string{"I got an A.", " Then I left."}},
+		{"middle initial", []string{"John F", ". Kennedy spoke. Yes."}, nil, []string{"John F. Kennedy spoke.", " Yes."}},
+		{"custom abbreviations", []string{"Mr. Smith. Ok."}, []SentenceOption{WithSentenceAbbreviations("Smith.")}, []string{"Mr.", " Smith. Ok."}},
+		{"newline", []string{"标题\n正文。"}, nil, []string{"标题\n", "正文。"}},
+		{"ellipsis", []string{"嗯……", "好吧"}, nil, []string{"嗯……", "好吧"}},
+		{"min length", []string{"好。", "我们走吧。出发！"}, []SentenceOption{WithSentenceLength(4, 0)}, []string{"好。我们走吧。", "出发！"}},
+		{"max length at soft break", []string{"一二三，四五六七八九十。"}, []SentenceOption{WithSentenceLength(0, 6)}, []string{"一二三，", "四五六七八九", "十。"}},
+		{"max length ignores leading space", []string{"Hello world"}, []SentenceOption{WithSentenceLength(0, 5)}, []string{"Hello", " world"}},
+		{"blank lines stay with next sentence", []string{"a.\n\nb"}, nil, []string{"a.", "\n\nb"}},
+		{"max length without soft break", []string{"abcdefgh"}, []SentenceOption{WithSentenceLength(0, 3)}, []string{"abc", "def", "gh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStream(t, SplitSentences(FromSlice(tt.input), tt.opts...), tt.want, io.EOF)
+		})
+	}
+
+	t.Run("emits sentence before upstream ends", func(t *testing.T) {
+		s := SplitSentences(blockingStream("第一句。第", "二句"))
+		if v, err := s.Recv(); v != "第一句。" || err != nil {
+			t.Fatalf("Recv() = %q, %v", v, err)
+		}
+	})
+
+	t.Run("flush on timeout", func(t *testing.T) {
+		s := SplitSentences(slowStream("还没说完", 100*time.Millisecond, "的话。"), WithSentenceParserOptions(WithMaxHoldback(10*time.Millisecond)))
+		expectStream(t, s, []string{"还没说完", "的话。"}, io.EOF)
+	})
+
+	t.Run("ToSentenceReader", func(t *testing.T) {
+		r := NewStringReader(FromSlice([]string{"<a>One. Two."}))
+		if v, err := r.ReadUntil([]string{">"}); v != "<a>" || err != nil {
+			t.Fatalf("ReadUntil() = %q, %v", v, err)
+		}
+		expectStream(t, r.ToSentenceReader(), []string{"One.", " Two."}, io.EOF)
+	})
+}
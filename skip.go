@@ -1,15 +1,17 @@
 package streams
 
+import "context"
+
 // SkipUntil 返回某个包之后的尾部流，尾部流的首包一定是那个满足条件的包
 func SkipUntil[T any](s Stream[T], f func(T) bool) Stream[T] {
 	hasSkipped := false
-	return FromFunc(func() (T, error) {
+	return newFuncStream(func(ctx context.Context) (T, error) {
 		var zero T
 		if hasSkipped {
-			return s.Recv()
+			return RecvContext(ctx, s)
 		}
 		for {
-			v, err := s.Recv()
+			v, err := RecvContext(ctx, s)
 			if err != nil {
 				return zero, err
 			}
@@ -18,16 +20,16 @@ func SkipUntil[T any](s Stream[T], f func(T) bool) Stream[T] {
 				return v, nil
 			}
 		}
-	})
+	}, closerOf(s))
 }
 
 // SkipN 返回跳过n个包之后的流
 func SkipN[T any](s Stream[T], n int) Stream[T] {
 	i := 0
-	return FromFunc(func() (T, error) {
+	return newFuncStream(func(ctx context.Context) (T, error) {
 		var zero T
 		for {
-			v, err := s.Recv()
+			v, err := RecvContext(ctx, s)
 			if err != nil {
 				return zero, err
 			}
@@ -36,5 +38,5 @@ func SkipN[T any](s Stream[T], n int) Stream[T] {
 			}
 			i++
 		}
-	})
+	}, closerOf(s))
 }
@@ -1,107 +1,87 @@
 package streams
 
 import (
+	"context"
 	"io"
-	"strings"
-	"unicode/utf8"
 )
 
 type specialTokenParserStream struct {
 	Stream[string]
 	specialTokens []string
-	minBufferLen  int
 
+	recv      holdbackReceiver
 	lastToken string
-	buffer    string
+	scanner   tokenScanner
+	eof       bool
 }
 
 type LabeledChunk struct {
 	Label string
 	Chunk string
+	Path  string // 只在NewLabelStream的嵌套模式下有值，是从外到内用"/"连接的label名，见WithNestedLabels
 }
 
-func NewSpecialTokenParserStream(src Stream[string], specialTokens []string) *specialTokenParserStream {
-	minBufferLen := 0
-	for _, lb := range specialTokens {
-		if utf8.RuneCountInString(lb) > minBufferLen {
-			minBufferLen = len(lb)
-		}
-	}
-
+// NewSpecialTokenParserStream 用遇到的上一个特殊标记给后面的文本打标签，每遇到一个特殊标记会先输出一个Chunk为空的LabeledChunk
+// 多个特殊标记之间，最早出现的优先；从同一位置开始时，排在前面的优先
+// 只有末尾可能是某个特殊标记前缀的文本才会被暂存，可以通过WithMaxHoldback限制暂存的时间
+func NewSpecialTokenParserStream(src Stream[string], specialTokens []string, opts ...ParserOption) *specialTokenParserStream {
+	src = avoidNil(src)
 	return &specialTokenParserStream{
-		Stream:        avoidNil(src),
+		Stream:        src,
+		recv:          newHoldbackReceiver(src, opts),
 		specialTokens: specialTokens,
-		minBufferLen:  minBufferLen,
+		scanner:       newTokenScanner(specialTokens),
 	}
 }
 
-func (s *specialTokenParserStream) cutOverflowBuffer() (label string, chunk string) {
-	if utf8.RuneCountInString(s.buffer) <= s.minBufferLen {
-		return "", ""
-	}
-
-	for _, st := range s.specialTokens {
-		index := strings.Index(s.buffer, st)
-		if index == -1 {
-			continue
-		}
-		// 遇到了某个特殊标记
-		prelastToken := s.lastToken
-		s.lastToken = st
-		chunkBeforeToken := s.buffer[:index]
-		s.buffer = s.buffer[index:]
-		if len(chunkBeforeToken) > 0 {
-			return prelastToken, chunkBeforeToken
-		} else {
-			// buffer以这个标记开头
-			s.buffer = s.buffer[len(st):]
-			return st, ""
-		}
+// cutOverflowBuffer 处理buffer中已经可以确定的数据，ok为false表示需要更多的上游数据
+func (s *specialTokenParserStream) cutOverflowBuffer() (lc LabeledChunk, ok bool) {
+	safe, match, found := s.scanner.scan(s.eof)
+	if !found {
+		chunk := s.scanner.cut(safe)
+		return LabeledChunk{Label: s.lastToken, Chunk: chunk}, chunk != ""
 	}
-
-	index := utf8.RuneCountInString(s.buffer) - s.minBufferLen
-
-	if index > 0 {
-		bufferRunes := []rune(s.buffer)
-		beforeChunk := string(bufferRunes[:index])
-		s.buffer = string(bufferRunes[index:])
-		return s.lastToken, beforeChunk
+	if match.start > 0 { // 先输出特殊标记之前的文本
+		return LabeledChunk{Label: s.lastToken, Chunk: s.scanner.cut(match.start)}, true
 	}
+	// buffer以这个标记开头
+	s.scanner.cut(match.end)
+	s.lastToken = s.specialTokens[match.token]
+	return LabeledChunk{Label: s.lastToken}, true
+}
 
-	return "", ""
+func (s *specialTokenParserStream) Close() error {
+	return s.recv.close()
 }
 
 func (s *specialTokenParserStream) Recv() (LabeledChunk, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *specialTokenParserStream) RecvContext(ctx context.Context) (LabeledChunk, error) {
 	for {
-		label, chunk := s.cutOverflowBuffer() // 先处理buffer中的数据
-		if chunk != "" || label != "" {
-			return LabeledChunk{
-				Label: label,
-				Chunk: chunk,
-			}, nil
+		if lc, ok := s.cutOverflowBuffer(); ok { // 先处理buffer中的数据
+			return lc, nil
+		}
+		if s.eof { // 上游已经读完了，buffer也处理完了
+			return LabeledChunk{}, io.EOF
 		}
 
-		upChunk, err := s.Stream.Recv()
-		if err == io.EOF { // 上游已经读完了，但是buffer中还有数据，需要处理
-			s.minBufferLen = 0
-			label, chunk := s.cutOverflowBuffer()
-			if chunk == "" && label == "" {
-				return LabeledChunk{}, io.EOF
-			} else {
-				return LabeledChunk{
-					Label: label,
-					Chunk: chunk,
-				}, nil
-			}
+		upChunk, err := s.recv.recv(ctx, len(s.scanner.buf) > 0)
+		if err == errHoldbackExpired { // 暂存超时，当作普通文本输出
+			return LabeledChunk{Label: s.lastToken, Chunk: s.scanner.cut(len(s.scanner.buf))}, nil
+		} else if err == io.EOF { // 上游已经读完了，但是buffer中还有数据，需要处理
+			s.eof = true
+			continue
 		} else if err != nil {
 			return LabeledChunk{}, err
 		}
-		s.buffer += upChunk
+		s.scanner.write(upChunk)
 	}
 }
 
 func (s *specialTokenParserStream) Demux() map[string]Stream[string] {
-	labels := make([]string, len(s.specialTokens))
+	labels := make([]string, 0, len(s.specialTokens))
 	for _, st := range s.specialTokens {
 		labels = append(labels, st)
 	}
@@ -0,0 +1,119 @@
+package streams
+
+import (
+	"context"
+	"io"
+)
+
+type stopSequenceStream struct {
+	src     holdbackReceiver
+	stops   []string
+	include bool
+
+	scanner tokenScanner
+	matched int // 命中的stop下标，-1表示还没有命中
+	tail    string
+	eof     bool
+}
+
+type StopSequenceOption func(*stopSequenceOptions)
+
+type stopSequenceOptions struct {
+	includeStop bool
+	parserOpts  []ParserOption
+}
+
+// WithIncludeStop 输出的文本包含命中的stop
+func WithIncludeStop() StopSequenceOption {
+	return func(o *stopSequenceOptions) {
+		o.includeStop = true
+	}
+}
+
+// WithStopSequenceParserOptions 传给底层解析器的选项，比如WithMaxHoldback
+func WithStopSequenceParserOptions(opts ...ParserOption) StopSequenceOption {
+	return func(o *stopSequenceOptions) {
+		o.parserOpts = append(o.parserOpts, opts...)
+	}
+}
+
+// NewStopSequenceStream 模拟模型的stop参数：遇到stops中任意一个字符串时截断，stop可以跨chunk
+// 注意事项：
+// 1. 默认不输出命中的stop，需要输出时使用WithIncludeStop
+// 2. 多个stop之间，最早出现的优先；从同一位置开始时，排在前面的优先
+// 3. 命中之后会立即关闭上游，输出完stop之前的文本后返回io.EOF，可以通过Matched获取命中的stop
+// 4. 只有末尾可能是某个stop前缀的文本才会被暂存，可以通过WithStopSequenceParserOptions(WithMaxHoldback(d))限制暂存的时间
+func NewStopSequenceStream(src Stream[string], stops []string, opts ...StopSequenceOption) *stopSequenceStream {
+	var o stopSequenceOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &stopSequenceStream{
+		src:     newHoldbackReceiver(src, o.parserOpts),
+		stops:   stops,
+		include: o.includeStop,
+		scanner: newTokenScanner(stops),
+		matched: -1,
+	}
+}
+
+// Matched 返回命中的stop，还没有命中时ok为false
+// 注意：一读到stop就会命中，这时stop之前的文本可能还没有输出完，要判断流是否因为stop结束，需要在读到io.EOF之后再调用
+func (s *stopSequenceStream) Matched() (stop string, ok bool) {
+	if s.matched < 0 {
+		return "", false
+	}
+	return s.stops[s.matched], true
+}
+
+func (s *stopSequenceStream) Recv() (string, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *stopSequenceStream) RecvContext(ctx context.Context) (string, error) {
+	for {
+		if s.matched >= 0 {
+			if s.tail != "" {
+				tail := s.tail
+				s.tail = ""
+				return tail, nil
+			}
+			return "", io.EOF
+		}
+
+		safe, match, found := s.scanner.scan(s.eof)
+		if found {
+			end := match.start
+			if s.include {
+				end = match.end
+			}
+			s.tail = s.scanner.cut(end)
+			s.matched = match.token
+			s.scanner.cut(len(s.scanner.buf))
+			s.eof = true
+			s.src.close() // 后面的数据都不需要了
+			continue
+		}
+		if chunk := s.scanner.cut(safe); chunk != "" {
+			return chunk, nil
+		}
+		if s.eof {
+			return "", io.EOF
+		}
+
+		upChunk, err := s.src.recv(ctx, len(s.scanner.buf) > 0)
+		if err == errHoldbackExpired { // 暂存超时，当作普通文本输出
+			return s.scanner.cut(len(s.scanner.buf)), nil
+		} else if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		s.scanner.write(upChunk)
+	}
+}
+
+func (s *stopSequenceStream) Close() error {
+	return s.src.close()
+}
@@ -0,0 +1,74 @@
+package streams
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopSequenceStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []string
+		stops  []string
+		opts   []StopSequenceOption
+		want   string
+		wantOK bool
+		stop   string
+	}{
+		{"across chunks", []string{"hello\nUs", "er: hi"}, []string{"\nUser:"}, nil, "hello", true, "\nUser:"},
+		{"include stop", []string{"a", "bc", "d"}, []string{"bc"}, []StopSequenceOption{WithIncludeStop()}, "abc", true, "bc"},
+		{"earliest stop", []string{"xENDySTOP"}, []string{"STOP", "END"}, nil, "x", true, "END"},
+		{"no match", []string{"he", "llo"}, []string{"</s>"}, nil, "hello", false, ""},
+		{"partial stop at eof", []string{"he", "llo</"}, []string{"</s>"}, nil, "hello</", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStopSequenceStream(FromSlice(tt.input), tt.stops, tt.opts...)
+			expectStringStream(t, s, tt.want, io.EOF)
+			if stop, ok := s.Matched(); ok != tt.wantOK || stop != tt.stop {
+				t.Fatalf("Matched() = %q, %v; want %q, %v", stop, ok, tt.stop, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("closes upstream on match", func(t *testing.T) {
+		src, closed := trackClose(blockingStream("a", "b<stop>c"))
+		s := NewStopSequenceStream(src, []string{"<stop>"})
+		expectStringStream(t, s, "ab", io.EOF)
+		if closed.Load() != 1 {
+			t.Fatal("upstream was not closed")
+		}
+	})
+
+	t.Run("matched before the text is drained", func(t *testing.T) {
+		s := NewStopSequenceStream(FromSlice([]string{"ab<stop>c"}), []string{"<stop>"})
+		if v, err := s.Recv(); v != "ab" || err != nil {
+			t.Fatalf("Recv() = %q, %v", v, err)
+		}
+		if stop, ok := s.Matched(); !ok || stop != "<stop>" {
+			t.Fatalf("Matched() = %q, %v", stop, ok)
+		}
+	})
+
+	t.Run("max holdback", func(t *testing.T) {
+		s := NewStopSequenceStream(slowStream("a<st", 100*time.Millisecond, "x"), []string{"<stop>"}, WithStopSequenceParserOptions(WithMaxHoldback(10*time.Millisecond)))
+		expectStream(t, s, []string{"a", "<st", "x"}, io.EOF)
+	})
+
+	t.Run("streams text before a match", func(t *testing.T) {
+		s := NewStopSequenceStream(blockingStream("hello <", "x"), []string{"<stop>"})
+		var got []string
+		for i := 0; i < 2; i++ {
+			v, err := s.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		if strings.Join(got, "|") != "hello |<x" {
+			t.Fatalf("got %q", got)
+		}
+	})
+}
@@ -1,72 +1,86 @@
 package streams
 
 import (
+	"context"
 	"io"
+	"slices"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"unicode/utf8"
 )
 
 type StringReader struct {
 	Stream[string]
-	buffer strings.Builder
+	src     holdbackReceiver
+	scanner tokenScanner // 已经读取但还没返回的数据
+	delims  []string     // scanner当前匹配的delims
 }
 
-func NewStringReader(stream Stream[string]) *StringReader {
-	return &StringReader{Stream: avoidNil(stream)}
+// NewStringReader opts目前只有WithMaxHoldback会影响ReadUntil
+func NewStringReader(stream Stream[string], opts ...ParserOption) *StringReader {
+	stream = avoidNil(stream)
+	return &StringReader{Stream: stream, src: newHoldbackReceiver(stream, opts)}
 }
 
 // ReadUntil 合并流中的字符串直到遇到 delim 中的任意一个
 // 注意事项：
 // 1. 如果delims长度为0，则会阻塞合并流中的所有字符串
 // 2. 如果有delim是""，则会将流切分为rune粒度
-// 3. 如果上游流中一个chunk包含多个delim，不会按最先出现的delim进行切分，而是按delims的顺序进行切分(可优化)
+// 3. 多个delim之间，最早出现的优先；从同一位置开始时，排在前面的优先
+// 4. 通过WithMaxHoldback创建的StringReader，超时还没遇到delim时会直接返回已经读到的数据
 func (b *StringReader) ReadUntil(delims []string) (string, error) {
+	return b.ReadUntilContext(context.Background(), delims)
+}
+
+// ReadUntilContext 同ReadUntil，ctx取消时返回ctx.Err()，已读取的数据保留在buffer中
+func (b *StringReader) ReadUntilContext(ctx context.Context, delims []string) (string, error) {
+	if b.scanner.matcher == nil || !slices.Equal(b.delims, delims) {
+		b.delims = slices.Clone(delims)
+		b.scanner.reset(delims)
+	}
+	runeMode := slices.Contains(delims, "")
 	for {
-		if b.buffer.Len() > 0 {
-			buffer := b.buffer.String()
-			for _, delim := range delims {
-				index := strings.Index(buffer, delim)
-				if index >= 0 {
-					b.buffer.Reset()
-					endIndex := index + len(delim)
-					if delim == "" {
-						_, size := utf8.DecodeRuneInString(buffer)
-						endIndex = size
-					}
-					b.buffer.WriteString(buffer[endIndex:])
-					return buffer[:endIndex], nil
-				}
-			}
+		if runeMode && len(b.scanner.buf) > 0 {
+			_, size := utf8.DecodeRuneInString(b.scanner.buf)
+			return b.scanner.cut(size), nil
+		}
+		if _, match, found := b.scanner.scan(false); found {
+			return b.scanner.cut(match.end), nil
 		}
 
-		v, err := b.Stream.Recv()
-		if err == io.EOF {
-			if b.buffer.Len() > 0 {
-				buffer := b.buffer.String()
-				b.buffer.Reset()
-				return buffer, nil
-			} else {
+		v, err := b.src.recv(ctx, len(b.scanner.buf) > 0)
+		if err == errHoldbackExpired {
+			return b.scanner.cut(len(b.scanner.buf)), nil
+		} else if err == io.EOF {
+			if len(b.scanner.buf) == 0 {
 				return "", io.EOF
 			}
+			if _, match, found := b.scanner.scan(true); found {
+				return b.scanner.cut(match.end), nil
+			}
+			return b.scanner.cut(len(b.scanner.buf)), nil
 		} else if err != nil {
 			return "", err
 		}
-		if len(v) > 0 {
-			b.buffer.WriteString(v)
-		}
+		b.scanner.write(v)
 	}
 }
 
 // Recv 先将buffer返回，再读取上游流中的数据。主要用途是在ReadUntil方法消费到某个token之后，形成一个新的Stream
 // 注意：由于清空了buffer，所以不可和ReadUntil方法并发调用(这样做也没意义)
 func (b *StringReader) Recv() (string, error) {
-	if b.buffer.Len() > 0 {
-		buffer := b.buffer.String()
-		b.buffer.Reset()
-		return buffer, nil
+	return b.RecvContext(context.Background())
+}
+
+func (b *StringReader) RecvContext(ctx context.Context) (string, error) {
+	if len(b.scanner.buf) > 0 {
+		return b.scanner.cut(len(b.scanner.buf)), nil
 	}
-	return b.Stream.Recv()
+	return b.src.recv(ctx, false)
+}
+
+func (b *StringReader) Close() error {
+	return b.src.close()
 }
 
 // ReadLine 合并流中的字符串直到遇到换行符
@@ -102,21 +116,44 @@ func (d delimsStringReader) Recv() (string, error) {
 	return d.src.ReadUntil(d.delims)
 }
 
+func (d delimsStringReader) RecvContext(ctx context.Context) (string, error) {
+	return d.src.ReadUntilContext(ctx, d.delims)
+}
+
+func (d delimsStringReader) Close() error {
+	return d.src.Close()
+}
+
 type onceStringStream struct {
 	Stream[string]
-	done atomic.Bool
+	mu   sync.Mutex      // 保护sb和done，并发的Recv依次执行
+	sb   strings.Builder // ctx取消时已经读到的数据，留给下一次读取
+	done bool
 }
 
 func (s *onceStringStream) Recv() (string, error) {
-	if s.done.Swap(true) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *onceStringStream) Close() error {
+	return Close(s.Stream)
+}
+
+func (s *onceStringStream) RecvContext(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
 		return "", io.EOF
 	}
-	var sb strings.Builder
-	err := Consume(s.Stream, func(v string) error {
-		sb.WriteString(v)
+	err := ConsumeContext(ctx, s.Stream, func(v string) error {
+		s.sb.WriteString(v)
 		return nil
 	})
-	return sb.String(), err
+	if isContextErr(ctx, err) {
+		return "", err
+	}
+	s.done = true
+	return s.sb.String(), err
 }
 
 // OnceStringStream 等到流都结束了，才将拼接好的字符串发送出来，在消费方想将某个流式突然想变成非流式时有用
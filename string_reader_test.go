@@ -1,7 +1,9 @@
 package streams
 
 import (
+	"fmt"
 	"io"
+	"slices"
 	"testing"
 )
 
@@ -61,3 +63,29 @@ func TestOnceStreamStream(t *testing.T) {
 	input := OnceStringStream(FromSlice([]string{"a", "b", "c"}))
 	expectStream(t, input, []string{"abc"}, io.EOF)
 }
+
+func TestOnceStreamStream_ContextCanceled(t *testing.T) {
+	ch := make(chan string, 1)
+	ch <- "a"
+	input := OnceStringStream(FromChan(ch))
+	expectCanceledPromptly(t, input)
+	ch <- "b"
+	close(ch)
+	expectStream(t, input, []string{"ab"}, io.EOF)
+}
+
+func TestOnceStringStream_ConcurrentRecv(t *testing.T) {
+	input := OnceStringStream(FromSlice([]string{"a", "b"}))
+	results := make(chan string, 2)
+	for range 2 {
+		go func() {
+			v, err := input.Recv()
+			results <- fmt.Sprintf("%q %v", v, err)
+		}()
+	}
+	got := []string{<-results, <-results}
+	slices.Sort(got)
+	if want := []string{`"" EOF`, `"ab" <nil>`}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
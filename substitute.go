@@ -1,31 +1,47 @@
 package streams
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
 
 type substitutingStream[T any] struct {
-	Stream[T]
+	cur     atomic.Pointer[Stream[T]] // 当前读取的流，Close不加锁，直接关闭它
 	replace func(T) Stream[T]
 
-	mu sync.Mutex
+	mu     sync.Mutex
+	closed atomic.Bool
 }
 
 // SubstituteStream 对流中每个数据执行replace函数，如果replace响应的流不为空，则用响应的新流替换原流
 // 注意事项：
 // 1. 当流被替换之后，replace函数不会再被调用
 // 2. 当流被替换之后，原流的数据不会撤销，因为下游已经接收到了
-// 3. 当流被替换之后，原流的剩余的数据会被丢弃，如果上游是FromChan创建的流，则可能导致协程泄露，为了避免这种情况，需要业务方在replace函数内起一个协程来消费完原流的所有数据
+// 3. 当流被替换之后，原流的剩余的数据会被丢弃，原流会被Close。如果原流没有实现io.Closer(比如FromFunc创建的流)，需要业务方自行释放原流
 func SubstituteStream[T any](src Stream[T], replace func(T) Stream[T]) Stream[T] {
-	return &substitutingStream[T]{
-		Stream:  avoidNil(src),
-		replace: replace,
-	}
+	s := &substitutingStream[T]{replace: replace}
+	src = avoidNil(src)
+	s.cur.Store(&src)
+	return s
+}
+
+// Close 不加锁，避免被阻塞中的Recv卡住
+func (s *substitutingStream[T]) Close() error {
+	s.closed.Store(true)
+	return Close(*s.cur.Load())
 }
 
 func (s *substitutingStream[T]) Recv() (T, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *substitutingStream[T]) RecvContext(ctx context.Context) (T, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	frame, err := s.Stream.Recv()
+	src := *s.cur.Load()
+	frame, err := RecvContext(ctx, src)
 	if err != nil {
 		return frame, err
 	}
@@ -36,8 +52,12 @@ func (s *substitutingStream[T]) Recv() (T, error) {
 
 	replacements := s.replace(frame)
 	if replacements != nil {
-		s.Stream = replacements
-		return s.Stream.Recv()
+		Close(src)
+		s.cur.Store(&replacements)
+		if s.closed.Load() { // 替换的同时被Close了
+			Close(replacements)
+		}
+		return RecvContext(ctx, replacements)
 	} else {
 		return frame, err
 	}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"testing"
+	"time"
 )
 
 func TestSubstituteStream_Recv(t *testing.T) {
@@ -98,3 +99,26 @@ func TestSubstituteStream_Recv(t *testing.T) {
 		expectStream(t, stream, []string{"start", "我是", "咨询小结"}, io.EOF)
 	})
 }
+
+func TestSubstituteStream_CloseWhileRecvBlocked(t *testing.T) {
+	ch := make(chan int)
+	defer close(ch)
+	src, closed := trackClose(FromChan(ch))
+	s := SubstituteStream(src, nil)
+	go s.Recv()
+	time.Sleep(10 * time.Millisecond) // 等待Recv阻塞在上游
+
+	done := make(chan struct{})
+	go func() {
+		Close(s)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close blocked by pending Recv")
+	}
+	if closed.Load() != 1 {
+		t.Fatal("upstream was not closed")
+	}
+}
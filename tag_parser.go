@@ -0,0 +1,231 @@
+package streams
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+)
+
+type TagEventKind int
+
+const (
+	TagText  TagEventKind = iota // 普通文本，包括不在白名单中的标签
+	TagOpen                      // 开始标签，自闭合标签<x/>会依次输出TagOpen和TagClose
+	TagClose                     // 结束标签
+)
+
+type TagEvent struct {
+	Kind  TagEventKind
+	Name  string            // TagOpen、TagClose时是标签名
+	Attrs map[string]string // TagOpen时是标签的属性，没有值的属性对应""
+	Text  string            // TagText时是文本
+}
+
+type tagParseStatus int
+
+const (
+	tagInvalid    tagParseStatus = iota // 不是白名单中的标签，当作普通文本
+	tagIncomplete                       // 可能是标签，需要更多数据
+	tagComplete
+)
+
+type tagParserStream struct {
+	src   holdbackReceiver
+	names []string
+
+	buf     string
+	pending []TagEvent // 自闭合标签的TagClose
+	eof     bool
+}
+
+// NewTagParser 增量解析流中类似XML的标签，比如<tool name="search" id="3">...</tool>，标签可以跨chunk
+// 注意事项：
+// 1. 只有names中的标签会被解析，其他标签原样作为TagText输出
+// 2. 属性值可以用双引号、单引号或者不加引号，不会做实体(&quot;等)解码；标签之间的嵌套关系由下游自行处理
+// 3. 只有可能是白名单标签前缀的文本才会被暂存，属性值中的引号一直没有闭合时会一直暂存，可以通过WithMaxHoldback限制暂存的时间
+func NewTagParser(src Stream[string], names []string, opts ...ParserOption) Stream[TagEvent] {
+	return &tagParserStream{
+		src:   newHoldbackReceiver(src, opts),
+		names: names,
+	}
+}
+
+func isTagNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == ':' || c == '.'
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// parseTag 解析以'<'开头的b，返回解析到的标签和长度，selfClosing表示是<x/>这样的自闭合标签
+func (s *tagParserStream) parseTag(b string) (ev TagEvent, n int, selfClosing bool, status tagParseStatus) {
+	pos := 1
+	skipSpace := func() {
+		for pos < len(b) && isTagSpace(b[pos]) {
+			pos++
+		}
+	}
+	readName := func() string {
+		start := pos
+		for pos < len(b) && isTagNameByte(b[pos]) {
+			pos++
+		}
+		return b[start:pos]
+	}
+
+	ev.Kind = TagOpen
+	if pos < len(b) && b[pos] == '/' {
+		ev.Kind = TagClose
+		pos++
+	}
+	ev.Name = readName()
+	if pos == len(b) { // 标签名可能还没有接收完
+		if slices.ContainsFunc(s.names, func(name string) bool { return strings.HasPrefix(name, ev.Name) }) {
+			return ev, 0, false, tagIncomplete
+		}
+		return ev, 0, false, tagInvalid
+	}
+	if !slices.Contains(s.names, ev.Name) {
+		return ev, 0, false, tagInvalid
+	}
+
+	if ev.Kind == TagClose {
+		skipSpace()
+		if pos == len(b) {
+			return ev, 0, false, tagIncomplete
+		}
+		if b[pos] != '>' {
+			return ev, 0, false, tagInvalid
+		}
+		return ev, pos + 1, false, tagComplete
+	}
+
+	ev.Attrs = make(map[string]string)
+	for {
+		skipSpace()
+		if pos == len(b) {
+			return ev, 0, false, tagIncomplete
+		}
+		switch b[pos] {
+		case '>':
+			return ev, pos + 1, false, tagComplete
+		case '/':
+			if pos+1 == len(b) {
+				return ev, 0, false, tagIncomplete
+			}
+			if b[pos+1] != '>' {
+				return ev, 0, false, tagInvalid
+			}
+			return ev, pos + 2, true, tagComplete
+		}
+
+		key := readName()
+		if key == "" {
+			return ev, 0, false, tagInvalid
+		}
+		skipSpace()
+		if pos == len(b) {
+			return ev, 0, false, tagIncomplete
+		}
+		if b[pos] != '=' { // 没有值的属性
+			ev.Attrs[key] = ""
+			continue
+		}
+		pos++
+		skipSpace()
+		if pos == len(b) {
+			return ev, 0, false, tagIncomplete
+		}
+		if q := b[pos]; q == '"' || q == '\'' {
+			end := strings.IndexByte(b[pos+1:], q)
+			if end < 0 {
+				return ev, 0, false, tagIncomplete
+			}
+			ev.Attrs[key] = b[pos+1 : pos+1+end]
+			pos += end + 2
+			continue
+		}
+		start := pos
+		for pos < len(b) && !isTagSpace(b[pos]) && b[pos] != '>' {
+			pos++
+		}
+		if pos == len(b) {
+			return ev, 0, false, tagIncomplete
+		}
+		ev.Attrs[key] = b[start:pos]
+	}
+}
+
+// next 处理buffer中的数据，ok为false表示需要更多的上游数据
+func (s *tagParserStream) next() (ev TagEvent, ok bool) {
+	if len(s.pending) > 0 {
+		ev = s.pending[0]
+		s.pending = s.pending[1:]
+		return ev, true
+	}
+	if s.buf == "" {
+		return ev, false
+	}
+
+	i := strings.IndexByte(s.buf, '<')
+	if i != 0 {
+		if i < 0 {
+			i = len(s.buf)
+		}
+		return s.cutText(i), true
+	}
+	ev, n, selfClosing, status := s.parseTag(s.buf)
+	if status == tagIncomplete && !s.eof {
+		return ev, false
+	}
+	if status != tagComplete { // 不是标签，'<'当作普通文本
+		j := strings.IndexByte(s.buf[1:], '<')
+		if j < 0 {
+			j = len(s.buf) - 1
+		}
+		return s.cutText(j + 1), true
+	}
+	s.buf = s.buf[n:]
+	if selfClosing {
+		s.pending = append(s.pending, TagEvent{Kind: TagClose, Name: ev.Name})
+	}
+	return ev, true
+}
+
+func (s *tagParserStream) cutText(n int) TagEvent {
+	text := s.buf[:n]
+	s.buf = s.buf[n:]
+	return TagEvent{Kind: TagText, Text: text}
+}
+
+func (s *tagParserStream) Recv() (TagEvent, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *tagParserStream) RecvContext(ctx context.Context) (TagEvent, error) {
+	for {
+		if ev, ok := s.next(); ok {
+			return ev, nil
+		}
+		if s.eof {
+			return TagEvent{}, io.EOF
+		}
+
+		chunk, err := s.src.recv(ctx, s.buf != "")
+		if err == errHoldbackExpired { // 暂存超时，当作普通文本输出
+			return s.cutText(len(s.buf)), nil
+		} else if err == io.EOF {
+			s.eof = true
+			continue
+		} else if err != nil {
+			return TagEvent{}, err
+		}
+		s.buf += chunk
+	}
+}
+
+func (s *tagParserStream) Close() error {
+	return s.src.close()
+}
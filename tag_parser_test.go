@@ -0,0 +1,92 @@
+package streams
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collectTagEvents 读取所有事件，相邻的TagText会被合并
+func collectTagEvents(t *testing.T, s Stream[TagEvent]) []TagEvent {
+	t.Helper()
+	var got []TagEvent
+	for {
+		ev, err := s.Recv()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n := len(got); n > 0 && ev.Kind == TagText && got[n-1].Kind == TagText {
+			got[n-1].Text += ev.Text
+			continue
+		}
+		got = append(got, ev)
+	}
+}
+
+func TestTagParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []TagEvent
+	}{
+		{
+			name:  "attributes",
+			input: `a<tool name="search" id='3' raw=x flag>q</tool>b`,
+			want: []TagEvent{
+				{Kind: TagText, Text: "a"},
+				{Kind: TagOpen, Name: "tool", Attrs: map[string]string{"name": "search", "id": "3", "raw": "x", "flag": ""}},
+				{Kind: TagText, Text: "q"},
+				{Kind: TagClose, Name: "tool"},
+				{Kind: TagText, Text: "b"},
+			},
+		},
+		{
+			name:  "quoted >",
+			input: `<tool q="a>b"/>`,
+			want: []TagEvent{
+				{Kind: TagOpen, Name: "tool", Attrs: map[string]string{"q": "a>b"}},
+				{Kind: TagClose, Name: "tool"},
+			},
+		},
+		{
+			name:  "unknown tags pass through",
+			input: `x<b>y</b> 1<2 <tools>`,
+			want:  []TagEvent{{Kind: TagText, Text: `x<b>y</b> 1<2 <tools>`}},
+		},
+		{
+			name:  "unfinished tag at eof",
+			input: `x<tool name="a`,
+			want:  []TagEvent{{Kind: TagText, Text: `x<tool name="a`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 逐字符输入和整体输入的结果应该一致
+			for _, chunks := range [][]string{strings.Split(tt.input, ""), {tt.input}} {
+				got := collectTagEvents(t, NewTagParser(FromSlice(chunks), []string{"tool"}))
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("holds back only possible tags", func(t *testing.T) {
+		ch := make(chan string, 1)
+		ch <- "hi <b"
+		s := NewTagParser(FromChan(ch), []string{"tool"})
+		ev, err := s.Recv()
+		if err != nil || ev.Text != "hi " {
+			t.Fatalf("Recv() = %+v, %v", ev, err)
+		}
+		ev, err = s.Recv()
+		if err != nil || ev.Text != "<b" {
+			t.Fatalf("Recv() = %+v, %v", ev, err)
+		}
+		close(ch)
+	})
+}
@@ -1,17 +1,20 @@
 package streams
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 func TakeWhile[T any](src Stream[T], canTake func(packet T) bool) Stream[T] {
 	var zero T
 	end := false
 
-	return FromFunc(func() (T, error) {
+	return newFuncStream(func(ctx context.Context) (T, error) {
 		for {
 			if end {
 				return zero, io.EOF
 			}
-			v, err := src.Recv()
+			v, err := RecvContext(ctx, src)
 			if err != nil {
 				return v, err
 			}
@@ -20,5 +23,5 @@ func TakeWhile[T any](src Stream[T], canTake func(packet T) bool) Stream[T] {
 			}
 			end = true
 		}
-	})
+	}, closerOf(src))
 }
@@ -0,0 +1,104 @@
+package streams
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+type ThinkOption func(*thinkOptions)
+
+type thinkOptions struct {
+	startTag      string
+	endTag        string
+	implicitStart bool
+	parserOpts    []ParserOption
+}
+
+// WithThinkTags 自定义推理块的标签，默认是<think>和</think>
+func WithThinkTags(startTag, endTag string) ThinkOption {
+	return func(o *thinkOptions) {
+		o.startTag, o.endTag = startTag, endTag
+	}
+}
+
+// WithImplicitThinkStart 流的开头没有开始标签时，也当作推理块处理，直到遇到结束标签。用于会省略开始标签的推理模型
+func WithImplicitThinkStart() ThinkOption {
+	return func(o *thinkOptions) {
+		o.implicitStart = true
+	}
+}
+
+// WithThinkParserOptions 传给底层NewLabelStream的选项，比如WithMaxHoldback
+func WithThinkParserOptions(opts ...ParserOption) ThinkOption {
+	return func(o *thinkOptions) {
+		o.parserOpts = append(o.parserOpts, opts...)
+	}
+}
+
+// SplitThinking 把推理模型的输出拆分为推理流和回答流，推理流是<think>和</think>之间的内容，其余内容属于回答流
+// 注意事项：
+// 1. 标签本身不会输出，两个流开头的空白都会被去掉
+// 2. 推理块没有结束时，流结束后推理流正常结束，回答流为空；只会去掉回答开头的结束标签，回答中间出现的结束标签原样保留
+// 3. 默认只有出现开始标签才进入推理块，模型会省略开始标签时使用WithImplicitThinkStart
+// 4. 两个流基于Demux，只消费其中一个时需要Close另一个，否则另一个流的数据会一直缓存
+func SplitThinking(src Stream[string], opts ...ThinkOption) (reasoning Stream[string], answer Stream[string]) {
+	o := thinkOptions{startTag: "<think>", endTag: "</think>"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	label := SLabel{Name: "think", StartToken: o.startTag, EndToken: o.endTag}
+	reasoningPrefix := ""
+	if o.implicitStart {
+		// 推理块从流的开头开始，开头如果有开始标签，则在推理流中去掉
+		label.StartToken = ""
+		reasoningPrefix = o.startTag
+	}
+	demux := NewLabelStream(src, []SLabel{label}, o.parserOpts...).Demux()
+	reasoning = trimStart(demux[label.Name], reasoningPrefix)
+	// 推理块的结束标签会留在回答流的开头
+	answer = trimStart(demux[""], o.endTag)
+	return reasoning, answer
+}
+
+// trimStart 去掉流开头的空白，prefix不为空时，再去掉紧跟着的prefix和它后面的空白
+func trimStart(src Stream[string], prefix string) Stream[string] {
+	src = avoidNil(src)
+	var buf string
+	var err error
+	trimmed := false
+	return newFuncStream(func(ctx context.Context) (string, error) {
+		for !trimmed {
+			var chunk string
+			chunk, err = RecvContext(ctx, src)
+			if isContextErr(ctx, err) {
+				return "", err
+			}
+			if err != nil { // 流已经结束，buf是prefix的一部分，原样输出
+				trimmed = true
+				if buf != "" {
+					return buf, nil
+				}
+				return "", err
+			}
+
+			buf = strings.TrimLeftFunc(buf+chunk, unicode.IsSpace)
+			if prefix != "" && strings.HasPrefix(buf, prefix) {
+				buf = strings.TrimLeftFunc(buf[len(prefix):], unicode.IsSpace)
+				prefix = ""
+			}
+			if buf == "" || prefix != "" && strings.HasPrefix(prefix, buf) {
+				continue // 还不能确定
+			}
+			trimmed = true
+			out := buf
+			buf = ""
+			return out, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return RecvContext(ctx, src)
+	}, closerOf(src))
+}
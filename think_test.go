@@ -0,0 +1,82 @@
+package streams
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSplitThinking(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		opts          []ThinkOption
+		wantReasoning string
+		wantAnswer    string
+	}{
+		{
+			name:          "normal",
+			input:         "<think>\nlet me see\n</think>\n\nthe answer",
+			wantReasoning: "let me see\n",
+			wantAnswer:    "the answer",
+		},
+		{
+			name:       "no think block",
+			input:      "  just answer",
+			wantAnswer: "just answer",
+		},
+		{
+			name:          "unterminated",
+			input:         "<think>still thinking",
+			wantReasoning: "still thinking",
+		},
+		{
+			name:          "missing open tag",
+			input:         "hmm\n</think>\nanswer",
+			opts:          []ThinkOption{WithImplicitThinkStart()},
+			wantReasoning: "hmm\n",
+			wantAnswer:    "answer",
+		},
+		{
+			name:          "implicit start with open tag",
+			input:         " <think> hmm</think>answer",
+			opts:          []ThinkOption{WithImplicitThinkStart()},
+			wantReasoning: "hmm",
+			wantAnswer:    "answer",
+		},
+		{
+			name:       "stray end tag without implicit start",
+			input:      "hmm</think>answer",
+			wantAnswer: "hmm</think>answer",
+		},
+		{
+			name:          "end tag inside answer",
+			input:         "<think>r</think>\nuse </think> to close",
+			wantReasoning: "r",
+			wantAnswer:    "use </think> to close",
+		},
+		{
+			name:          "custom tags",
+			input:         "<reasoning>r</reasoning>a",
+			opts:          []ThinkOption{WithThinkTags("<reasoning>", "</reasoning>")},
+			wantReasoning: "r",
+			wantAnswer:    "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasoning, answer := SplitThinking(FromSlice(strings.Split(tt.input, "")), tt.opts...)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				expectStringStream(t, reasoning, tt.wantReasoning, io.EOF)
+			}()
+			expectStringStream(t, answer, tt.wantAnswer, io.EOF)
+			<-done
+		})
+	}
+
+	t.Run("trimStart keeps partial prefix at eof", func(t *testing.T) {
+		expectStringStream(t, trimStart(FromSlice([]string{" ", "<thi"}), "<think>"), "<thi", io.EOF)
+	})
+}
@@ -0,0 +1,206 @@
+package streams
+
+import (
+	"cmp"
+	"slices"
+)
+
+// tokenMatcher 基于Aho-Corasick自动机的多模式匹配器，所有文本解析器(label、special token、remove tokens)共用
+// 自动机按字节构建，token是合法的utf8字符串时，匹配结果和切分位置都落在rune边界上
+type tokenMatcher struct {
+	tokens []string
+	nodes  []acNode
+}
+
+type acNode struct {
+	next     []acEdge // 按字节排序，大词典下绝大部分节点只有一个子节点，比map省内存
+	fail     int32
+	depth    int   // 从根节点到该节点的字节数，即该节点代表的token前缀的长度
+	out      int32 // 在该节点结束的token下标，-1表示没有
+	dictLink int32 // 沿fail链找到的第一个有out的节点，-1表示没有
+	minToken int32 // 子树中最小的token下标，用于判断是否还可能匹配到优先级更高的更长token
+}
+
+type acEdge struct {
+	b    byte
+	node int32
+}
+
+// child 返回node读入b之后的子节点
+func (n *acNode) child(b byte) (int32, bool) {
+	i, ok := slices.BinarySearchFunc(n.next, b, func(e acEdge, b byte) int { return cmp.Compare(e.b, b) })
+	if !ok {
+		return 0, false
+	}
+	return n.next[i].node, true
+}
+
+const noToken = int32(1<<31 - 1)
+
+// newTokenMatcher 构建自动机，空token会被忽略，重复的token以第一次出现的下标为准
+// token的下标越小优先级越高，只在多个token从同一位置开始匹配时才有意义
+func newTokenMatcher(tokens []string) *tokenMatcher {
+	m := &tokenMatcher{tokens: tokens}
+	m.nodes = append(m.nodes, acNode{out: -1, dictLink: -1, minToken: noToken})
+	for i, token := range tokens {
+		if token == "" {
+			continue
+		}
+		cur := int32(0)
+		m.nodes[0].minToken = min(m.nodes[0].minToken, int32(i))
+		for j := 0; j < len(token); j++ {
+			next, ok := m.nodes[cur].child(token[j])
+			if !ok {
+				next = int32(len(m.nodes))
+				m.nodes = append(m.nodes, acNode{depth: j + 1, out: -1, dictLink: -1, minToken: noToken})
+				edges := m.nodes[cur].next
+				i, _ := slices.BinarySearchFunc(edges, token[j], func(e acEdge, b byte) int { return cmp.Compare(e.b, b) })
+				m.nodes[cur].next = slices.Insert(edges, i, acEdge{b: token[j], node: next})
+			}
+			cur = next
+			m.nodes[cur].minToken = min(m.nodes[cur].minToken, int32(i))
+		}
+		if m.nodes[cur].out < 0 {
+			m.nodes[cur].out = int32(i)
+		}
+	}
+
+	// 按层遍历计算fail和dictLink
+	queue := make([]int32, 0, len(m.nodes))
+	for _, e := range m.nodes[0].next {
+		queue = append(queue, e.node)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, e := range m.nodes[cur].next {
+			child := e.node
+			m.nodes[child].fail = m.step(m.nodes[cur].fail, e.b)
+			fail := m.nodes[child].fail
+			if m.nodes[fail].out >= 0 {
+				m.nodes[child].dictLink = fail
+			} else {
+				m.nodes[child].dictLink = m.nodes[fail].dictLink
+			}
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// step 从state读入一个字节之后的状态
+func (m *tokenMatcher) step(state int32, b byte) int32 {
+	for {
+		if next, ok := m.nodes[state].child(b); ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = m.nodes[state].fail
+	}
+}
+
+type tokenMatch struct {
+	start int // token在buffer中的起始位置
+	end   int
+	token int // token下标
+}
+
+// tokenScanner 在不断追加的buffer上增量地查找token，已经扫描过的字节不会重复扫描
+// 匹配规则是最早开始的token优先，多个token从同一位置开始时下标小的优先
+// 没有确定的匹配时，只会保留buffer末尾真正可能是某个token前缀的部分，其余部分都可以安全输出
+type tokenScanner struct {
+	matcher *tokenMatcher
+	buf     string
+	scanned int   // buf[:scanned]已经喂给自动机
+	state   int32 // 自动机当前状态，代表buf[:scanned]最长的、同时是某个token前缀的后缀
+	cand    tokenMatch
+	hasCand bool // 已经找到但还不能确定的匹配，可能被更早开始的token取代
+}
+
+func newTokenScanner(tokens []string) tokenScanner {
+	return tokenScanner{matcher: newTokenMatcher(tokens)}
+}
+
+func (s *tokenScanner) write(chunk string) {
+	s.buf += chunk
+}
+
+// unread 把text放回buffer的开头，和后面的数据一起重新扫描
+func (s *tokenScanner) unread(text string) {
+	s.buf = text + s.buf
+	s.rescan()
+}
+
+// reset 切换匹配的token，buffer中的数据会用新的token重新扫描
+func (s *tokenScanner) reset(tokens []string) {
+	s.matcher = newTokenMatcher(tokens)
+	s.rescan()
+}
+
+func (s *tokenScanner) rescan() {
+	s.scanned = 0
+	s.state = 0
+	s.hasCand = false
+}
+
+// committable 判断候选匹配是否已经确定，即正在匹配中的token前缀都不可能比它更优先
+func (s *tokenScanner) committable() bool {
+	if !s.hasCand {
+		return false
+	}
+	node := &s.matcher.nodes[s.state]
+	inProgress := s.scanned - node.depth // 正在匹配中的最长前缀的起始位置
+	return inProgress > s.cand.start || (inProgress == s.cand.start && node.minToken >= int32(s.cand.token))
+}
+
+// offer 用新找到的匹配更新候选匹配
+func (s *tokenScanner) offer(m tokenMatch) {
+	if !s.hasCand || m.start < s.cand.start || (m.start == s.cand.start && m.token < s.cand.token) {
+		s.cand = m
+		s.hasCand = true
+	}
+}
+
+// scan 扫描buffer，找到确定的匹配时返回该匹配；否则返回buffer中可以安全输出的前缀长度
+// flush为true时表示不会再有新数据了，候选匹配直接确定，剩下的数据也都可以输出
+func (s *tokenScanner) scan(flush bool) (safe int, match tokenMatch, found bool) {
+	nodes := s.matcher.nodes
+	for !s.committable() && s.scanned < len(s.buf) {
+		s.state = s.matcher.step(s.state, s.buf[s.scanned])
+		s.scanned++
+		// 沿dictLink遍历所有在当前位置结束的token
+		for n := s.state; n >= 0; n = nodes[n].dictLink {
+			if out := nodes[n].out; out >= 0 {
+				s.offer(tokenMatch{start: s.scanned - nodes[n].depth, end: s.scanned, token: int(out)})
+			}
+		}
+	}
+	if s.hasCand && (flush || s.committable()) {
+		return s.cand.start, s.cand, true
+	}
+	if flush {
+		return len(s.buf), tokenMatch{}, false
+	}
+	safe = s.scanned - nodes[s.state].depth // 正在匹配中的前缀之前的数据都是安全的
+	if s.hasCand {
+		safe = min(safe, s.cand.start)
+	}
+	return safe, tokenMatch{}, false
+}
+
+// cut 取出buffer的前n个字节
+func (s *tokenScanner) cut(n int) string {
+	text := s.buf[:n]
+	s.buf = s.buf[n:]
+	if n > s.scanned-s.matcher.nodes[s.state].depth || (s.hasCand && n > s.cand.start) {
+		// 切到了正在匹配的部分，剩下的数据需要重新扫描，最多重新扫描一个token的长度
+		s.rescan()
+	} else {
+		s.scanned -= n
+		s.cand.start -= n
+		s.cand.end -= n
+	}
+	return text
+}
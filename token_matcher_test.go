@@ -0,0 +1,68 @@
+package streams
+
+import (
+	"strings"
+	"testing"
+)
+
+// scanAll 逐字节喂给tokenScanner，返回切分结果，token用[]包起来
+func scanAll(tokens []string, input string) string {
+	s := newTokenScanner(tokens)
+	var out strings.Builder
+	drain := func(flush bool) {
+		for {
+			safe, match, found := s.scan(flush)
+			if !found {
+				out.WriteString(s.cut(safe))
+				return
+			}
+			out.WriteString(s.cut(match.start))
+			out.WriteString("[" + s.cut(match.end-match.start) + "]")
+		}
+	}
+	for i := 0; i < len(input); i++ {
+		s.write(input[i : i+1])
+		drain(false)
+	}
+	drain(true)
+	return out.String()
+}
+
+func TestTokenScanner(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []string
+		input  string
+		want   string
+	}{
+		{"earliest match", []string{"bc", "ab"}, "abcd", "[ab]cd"},
+		{"same start prefers lower index", []string{"ab", "abc"}, "abcd", "[ab]cd"},
+		{"longer token with lower index", []string{"abc", "ab"}, "abcd", "[abc]d"},
+		{"shorter token inside longer one", []string{"abcd", "bc"}, "abce", "a[bc]e"},
+		{"longer token wins when earlier", []string{"abcd", "bc"}, "abcde", "[abcd]e"},
+		{"partial token at eof", []string{"<x>"}, "a<x", "a<x"},
+		{"multibyte", []string{"【结论】"}, "前【结论】后", "前[【结论】]后"},
+		{"empty token ignored", []string{"", "b"}, "abc", "a[b]c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scanAll(tt.tokens, tt.input); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("holds back only a real prefix", func(t *testing.T) {
+		s := newTokenScanner([]string{"<|end|>"})
+		s.write("hello<|e")
+		safe, _, found := s.scan(false)
+		if found || safe != len("hello") {
+			t.Fatalf("scan() = %d, %v; want %d, false", safe, found, len("hello"))
+		}
+		s.write("x")
+		safe, _, found = s.scan(false)
+		if found || safe != len("hello<|ex") {
+			t.Fatalf("scan() = %d, %v; want %d, false", safe, found, len("hello<|ex"))
+		}
+	})
+}
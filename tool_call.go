@@ -0,0 +1,152 @@
+package streams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ErrInvalidToolCall 工具调用的增量不合法，或者arguments不是完整的JSON
+var ErrInvalidToolCall = errors.New("streams: invalid tool call")
+
+// ToolCallDelta OpenAI风格的工具调用增量，同一个调用的多个增量有相同的Index
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string // arguments新增的部分
+}
+
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // 完整的JSON
+}
+
+// ToolCallChunk AggregateToolCalls的输出，ToolCall为nil时是透传的文本
+type ToolCallChunk struct {
+	Content  string
+	ToolCall *ToolCall
+}
+
+type toolCallAggregator[D any] struct {
+	src     Stream[D]
+	extract func(D) (content string, calls []ToolCallDelta)
+
+	pending   []int // 还没有结束的调用的Index，从小到大
+	calls     map[int]*ToolCall
+	closedMax int // 已经结束的最大Index
+	out       []ToolCallChunk
+	err       error // 输出完out之后返回这个错误
+}
+
+// AggregateToolCalls 把流式响应中的工具调用增量聚合成完整的ToolCall，文本内容原样透传
+// extract从上游的每个数据中取出文本内容和工具调用增量，D通常是各个SDK的流式响应类型
+// 注意事项：
+// 1. 出现更大Index的增量时，更小Index的调用视为结束，上游返回io.EOF时所有调用结束，结束的调用按Index从小到大输出；上游返回其它错误时，没有结束的调用会被丢弃，错误原样返回
+// 2. ID和Name以第一次出现的非空值为准，Arguments按顺序拼接
+// 3. 调用结束时校验Arguments是完整的JSON(空的Arguments视为{})，不合法或者已经结束的调用又收到增量时，返回包装了ErrInvalidToolCall的错误
+func AggregateToolCalls[D any](src Stream[D], extract func(D) (content string, calls []ToolCallDelta)) Stream[ToolCallChunk] {
+	return &toolCallAggregator[D]{
+		src:       avoidNil(src),
+		extract:   extract,
+		calls:     make(map[int]*ToolCall),
+		closedMax: -1,
+	}
+}
+
+func (a *toolCallAggregator[D]) Recv() (ToolCallChunk, error) {
+	return a.RecvContext(context.Background())
+}
+
+func (a *toolCallAggregator[D]) RecvContext(ctx context.Context) (ToolCallChunk, error) {
+	for {
+		if len(a.out) > 0 {
+			c := a.out[0]
+			a.out = a.out[1:]
+			return c, nil
+		}
+		if a.err != nil {
+			return ToolCallChunk{}, a.err
+		}
+
+		d, err := RecvContext(ctx, a.src)
+		if isContextErr(ctx, err) {
+			return ToolCallChunk{}, err
+		}
+		if err == io.EOF {
+			a.closeBefore(-1)
+			if a.err == nil {
+				a.err = err
+			}
+			continue
+		} else if err != nil { // 上游出错时调用可能不完整，丢弃没有结束的调用，原样返回错误
+			a.pending = nil
+			clear(a.calls)
+			a.err = err
+			continue
+		}
+		content, deltas := a.extract(d)
+		if content != "" {
+			a.out = append(a.out, ToolCallChunk{Content: content})
+		}
+		for _, delta := range deltas {
+			if !a.merge(delta) {
+				break
+			}
+		}
+	}
+}
+
+// merge 合并一个增量，返回false表示出错了
+func (a *toolCallAggregator[D]) merge(delta ToolCallDelta) bool {
+	if delta.Index <= a.closedMax {
+		a.err = fmt.Errorf("%w: delta for finished call %d", ErrInvalidToolCall, delta.Index)
+		return false
+	}
+	if !a.closeBefore(delta.Index) {
+		return false
+	}
+	call, ok := a.calls[delta.Index]
+	if !ok {
+		call = &ToolCall{}
+		a.calls[delta.Index] = call
+		i, _ := slices.BinarySearch(a.pending, delta.Index)
+		a.pending = slices.Insert(a.pending, i, delta.Index)
+	}
+	if call.ID == "" {
+		call.ID = delta.ID
+	}
+	if call.Name == "" {
+		call.Name = delta.Name
+	}
+	call.Arguments += delta.Arguments
+	return true
+}
+
+// closeBefore 结束Index小于index的调用，index<0时结束所有调用，返回false表示出错了
+func (a *toolCallAggregator[D]) closeBefore(index int) bool {
+	for len(a.pending) > 0 && (index < 0 || a.pending[0] < index) {
+		i := a.pending[0]
+		a.pending = slices.Delete(a.pending, 0, 1)
+		call := a.calls[i]
+		delete(a.calls, i)
+		a.closedMax = i
+		if call.Arguments == "" {
+			call.Arguments = "{}"
+		}
+		if !json.Valid([]byte(call.Arguments)) {
+			a.err = fmt.Errorf("%w: call %d (%s) arguments are not complete json: %q", ErrInvalidToolCall, i, call.Name, call.Arguments)
+			return false
+		}
+		a.out = append(a.out, ToolCallChunk{ToolCall: call})
+	}
+	return true
+}
+
+func (a *toolCallAggregator[D]) Close() error {
+	return Close(a.src)
+}
@@ -0,0 +1,86 @@
+package streams
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+type testDelta struct {
+	content string
+	calls   []ToolCallDelta
+}
+
+func extractTestDelta(d testDelta) (string, []ToolCallDelta) {
+	return d.content, d.calls
+}
+
+// flattenToolCalls 把ToolCallChunk转换成可比较的字符串
+func flattenToolCalls(s Stream[ToolCallChunk]) Stream[string] {
+	return Map(s, func(c ToolCallChunk) string {
+		if c.ToolCall == nil {
+			return c.Content
+		}
+		return "call:" + c.ToolCall.ID + ":" + c.ToolCall.Name + ":" + c.ToolCall.Arguments
+	})
+}
+
+func TestAggregateToolCalls(t *testing.T) {
+	t.Run("sequential calls", func(t *testing.T) {
+		src := FromSlice([]testDelta{
+			{content: "let me "},
+			{content: "check", calls: []ToolCallDelta{{Index: 0, ID: "a", Name: "search", Arguments: `{"q":`}}},
+			{calls: []ToolCallDelta{{Index: 0, Arguments: `"go"}`}}},
+			{calls: []ToolCallDelta{{Index: 1, ID: "b", Name: "now"}}},
+		})
+		s := flattenToolCalls(AggregateToolCalls(src, extractTestDelta))
+		expectStream(t, s, []string{"let me ", "check", `call:a:search:{"q":"go"}`, "call:b:now:{}"}, io.EOF)
+	})
+
+	t.Run("several deltas in one item", func(t *testing.T) {
+		src := FromSlice([]testDelta{
+			{calls: []ToolCallDelta{{Index: 1, ID: "b", Name: "y", Arguments: "[1"}, {Index: 0, ID: "a", Name: "x", Arguments: "1"}}},
+			{calls: []ToolCallDelta{{Index: 1, Arguments: "]"}}},
+		})
+		s := flattenToolCalls(AggregateToolCalls(src, extractTestDelta))
+		expectStream(t, s, []string{"call:a:x:1", "call:b:y:[1]"}, io.EOF)
+	})
+
+	t.Run("incomplete arguments", func(t *testing.T) {
+		src := FromSlice([]testDelta{{calls: []ToolCallDelta{{Index: 0, Name: "x", Arguments: `{"q":`}}}})
+		_, err := AggregateToolCalls(src, extractTestDelta).Recv()
+		if !errors.Is(err, ErrInvalidToolCall) {
+			t.Fatalf("expected ErrInvalidToolCall, got %v", err)
+		}
+	})
+
+	t.Run("delta for finished call", func(t *testing.T) {
+		src := FromSlice([]testDelta{
+			{calls: []ToolCallDelta{{Index: 0, Name: "x"}}},
+			{calls: []ToolCallDelta{{Index: 1, Name: "y"}}},
+			{calls: []ToolCallDelta{{Index: 0, Arguments: "{}"}}},
+		})
+		s := flattenToolCalls(AggregateToolCalls(src, extractTestDelta))
+		expectStream(t, s, []string{"call::x:{}"}, ErrInvalidToolCall)
+	})
+
+	t.Run("upstream error", func(t *testing.T) {
+		boom := errors.New("boom")
+		src := Concat(FromSlice([]testDelta{{calls: []ToolCallDelta{{Index: 0, Name: "x", Arguments: "{}"}}}}), FromErr[testDelta](boom))
+		s := flattenToolCalls(AggregateToolCalls(src, extractTestDelta))
+		expectStream(t, s, nil, boom) // 上游出错时调用不一定完整，直接丢弃
+	})
+
+	t.Run("upstream error in the middle of arguments", func(t *testing.T) {
+		boom := errors.New("boom")
+		src := Concat(FromSlice([]testDelta{
+			{calls: []ToolCallDelta{{Index: 0, Name: "x", Arguments: "{}"}}},
+			{calls: []ToolCallDelta{{Index: 1, Name: "y", Arguments: `{"a":`}}},
+		}), FromErr[testDelta](boom))
+		s := flattenToolCalls(AggregateToolCalls(src, extractTestDelta))
+		expectStream(t, s, []string{"call::x:{}"}, boom)
+		if _, err := s.Recv(); !errors.Is(err, boom) || errors.Is(err, ErrInvalidToolCall) {
+			t.Fatalf("err = %v", err)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package streams
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+type TypewriterOption func(*typewriterOptions)
+
+type typewriterOptions struct {
+	maxLag   time.Duration
+	interval time.Duration
+}
+
+// WithTypewriterMaxLag 积压的文本按原速度不能在d内输出完时加速，使积压的文本大约在d内输出完，默认2秒
+func WithTypewriterMaxLag(d time.Duration) TypewriterOption {
+	return func(o *typewriterOptions) {
+		o.maxLag = d
+	}
+}
+
+// WithTypewriterInterval 两次输出之间的最小间隔，速度快时每次输出多个字符，默认30毫秒
+func WithTypewriterInterval(d time.Duration) TypewriterOption {
+	return func(o *typewriterOptions) {
+		o.interval = d
+	}
+}
+
+type typewriterStream struct {
+	src  holdbackReceiver
+	rate float64 // 每秒输出的字符数
+	opts typewriterOptions
+
+	buf   string
+	runes int       // buf中的字符数
+	next  time.Time // 下一次可以输出的时间
+	err   error     // 上游结束的原因，buf输出完之后返回
+}
+
+// Typewriter 按打字机的效果匀速输出文本，把上游突发的大包拆成每秒runesPerSecond个字符的小包，和只合并不拆包的ThrottleMerge互补
+// 注意事项：
+// 1. 等待输出期间会在后台继续读取上游，积压太多时按WithTypewriterMaxLag加速
+// 2. 上游结束(包括出错)时立即输出剩下的全部文本，然后返回上游的错误
+// 3. 输出之间没有数据时不会补偿，新的数据到达后立即开始输出
+func Typewriter(src Stream[string], runesPerSecond float64, opts ...TypewriterOption) Stream[string] {
+	if runesPerSecond <= 0 {
+		panic(fmt.Sprintf("streams: typewriter runesPerSecond %v must be positive", runesPerSecond))
+	}
+	o := typewriterOptions{maxLag: 2 * time.Second, interval: 30 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &typewriterStream{src: newHoldbackReceiver(src, nil), rate: runesPerSecond, opts: o}
+}
+
+func (s *typewriterStream) Recv() (string, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *typewriterStream) RecvContext(ctx context.Context) (string, error) {
+	for {
+		if s.buf == "" && s.err != nil {
+			return "", s.err
+		}
+		if s.err != nil { // 上游已经结束，不再等待
+			return s.cut(s.runes), nil
+		}
+
+		var deadline time.Time
+		if s.buf != "" {
+			if !time.Now().Before(s.next) {
+				return s.emit(), nil
+			}
+			deadline = s.next
+		}
+		chunk, err := s.src.recvUntil(ctx, deadline)
+		if err == errHoldbackExpired {
+			continue
+		} else if isContextErr(ctx, err) {
+			return "", err
+		} else if err != nil {
+			s.err = err
+			continue
+		}
+		s.buf += chunk
+		s.runes += utf8.RuneCountInString(chunk)
+	}
+}
+
+// emit 按当前速度输出一个间隔的字符，并计算下一次输出的时间
+func (s *typewriterStream) emit() string {
+	rate := s.rate
+	if lag := s.opts.maxLag.Seconds(); lag > 0 {
+		rate = max(rate, float64(s.runes)/lag)
+	}
+	n := max(1, int(rate*s.opts.interval.Seconds()))
+	s.next = time.Now().Add(time.Duration(float64(n) / rate * float64(time.Second)))
+	return s.cut(min(n, s.runes))
+}
+
+// cut 取出buf的前n个字符
+func (s *typewriterStream) cut(n int) string {
+	i := 0
+	for range n {
+		_, size := utf8.DecodeRuneInString(s.buf[i:])
+		i += size
+	}
+	text := s.buf[:i]
+	s.buf = s.buf[i:]
+	s.runes -= n
+	return text
+}
+
+func (s *typewriterStream) Close() error {
+	return s.src.close()
+}
@@ -0,0 +1,60 @@
+package streams
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTypewriter(t *testing.T) {
+	t.Run("splits bursty chunk", func(t *testing.T) {
+		start := time.Now()
+		s := Typewriter(slowStream("你好世界", 300*time.Millisecond), 100, WithTypewriterInterval(10*time.Millisecond))
+		var got []string
+		for len(got) < 4 {
+			v, err := s.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, v)
+		}
+		if strings.Join(got, "|") != "你|好|世|界" {
+			t.Fatalf("got %q", got)
+		}
+		if cost := time.Since(start); cost < 25*time.Millisecond || cost > 250*time.Millisecond {
+			t.Fatalf("cost %s", cost)
+		}
+		expectStream(t, s, nil, io.EOF)
+	})
+
+	t.Run("flushes on eof", func(t *testing.T) {
+		s := Typewriter(FromSlice([]string{"abc", "def"}), 1)
+		start := time.Now()
+		expectStream(t, s, []string{"a", "bcdef"}, io.EOF)
+		if cost := time.Since(start); cost > 500*time.Millisecond {
+			t.Fatalf("cost %s", cost)
+		}
+	})
+
+	t.Run("speeds up with backlog", func(t *testing.T) {
+		s := Typewriter(blockingStream(strings.Repeat("x", 100)), 10, WithTypewriterMaxLag(50*time.Millisecond), WithTypewriterInterval(10*time.Millisecond))
+		start := time.Now()
+		total, chunks := 0, 0
+		for total < 100 {
+			v, err := s.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += len(v)
+			chunks++
+		}
+		if cost := time.Since(start); cost > time.Second || chunks < 3 {
+			t.Fatalf("cost %s with %d chunks", cost, chunks)
+		}
+	})
+
+	t.Run("ctx cancel", func(t *testing.T) {
+		expectCanceledPromptly(t, Typewriter(blockingStream[string](), 10))
+	})
+}
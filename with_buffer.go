@@ -1,38 +1,67 @@
 package streams
 
 import (
-	"sync"
+	"context"
 )
 
+// WithBuffer 起一个goroutine提前读取上游，读到的数据缓存在内存中，下游消费时直接从缓存中取
+// 流被关闭时，goroutine会停止读取并关闭上游
 func WithBuffer[T any](src Stream[T]) Stream[T] {
 	type valWithErr struct {
 		val T
 		err error
 	}
+	src = avoidNil(src)
 	queue := &ConcurrentQueue[valWithErr]{}
-	var mu sync.Mutex
-	cond := sync.NewCond(&mu)
+	notify := make(chan struct{}, 1)
+	lock := make(chan struct{}, 1) // 用chan实现的锁，等待锁时可以响应ctx
+	bgCtx, cancel := context.WithCancel(context.Background())
 	go func() {
 		for {
-			val, err := src.Recv()
+			val, err := RecvContext(bgCtx, src)
+			if isContextErr(bgCtx, err) {
+				err = ErrClosed
+			}
 			queue.Push(valWithErr{val: val, err: err})
-			cond.Signal()
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
 			if err != nil {
 				return
 			}
 		}
 	}()
 
-	return FromFunc(func() (T, error) {
-		mu.Lock()
-		defer mu.Unlock()
+	var last *valWithErr // 上游已结束，后续的Recv都返回同一个错误
+	return newFuncStream(func(ctx context.Context) (T, error) {
+		var zero T
+		select {
+		case lock <- struct{}{}:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+		defer func() { <-lock }()
 
 		for {
+			if last != nil {
+				return last.val, last.err
+			}
 			val, ok := queue.Pop()
 			if ok {
+				if val.err != nil {
+					last = &val
+				}
 				return val.val, val.err
 			}
-			cond.Wait()
+			select {
+			case <-notify:
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
 		}
+	}, func() error {
+		cancel()
+		return Close(src)
 	})
 }
@@ -1,6 +1,7 @@
 package streams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,6 +30,10 @@ func WithLog[T any](stream Stream[T], key string, log func(info string)) Stream[
 }
 
 func (s *streamWithLog[T]) Recv() (T, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *streamWithLog[T]) RecvContext(ctx context.Context) (T, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -38,9 +43,9 @@ func (s *streamWithLog[T]) Recv() (T, error) {
 		s.log(fmt.Sprintf("[StreamLog] stream %s start to consume", s.key))
 	}
 
-	c, err := s.Stream.Recv()
+	c, err := RecvContext(ctx, s.Stream)
 
-	if s.stop {
+	if s.stop || isContextErr(ctx, err) { // ctx取消只影响本次调用，换一个ctx还可以继续读取
 		return c, err
 	}
 
@@ -57,6 +62,20 @@ func (s *streamWithLog[T]) Recv() (T, error) {
 	return c, err
 }
 
+// Close 先关闭上游(不加锁，避免被阻塞中的Recv卡住)，如果流还没有消费完，打印一次提前关闭的日志
+func (s *streamWithLog[T]) Close() error {
+	err := Close(s.Stream)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.startAt != nil && !s.stop {
+		cost := time.Now().Sub(*s.startAt)
+		s.log(fmt.Sprintf("[StreamLog] stream %s closed before completion, cost %s, %d items: %s", s.key, cost.String(), len(s.collect), s.allToString()))
+	}
+	s.stop = true
+	return err
+}
+
 func (s *streamWithLog[T]) allToString() string {
 	switch v := any(s.collect).(type) {
 	case []string:
@@ -1,6 +1,8 @@
 package streams
 
 import (
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -16,3 +18,17 @@ func TestWithLog(t *testing.T) {
 		t.Errorf("res is %s", res)
 	}
 }
+
+func TestWithLog_ContextCanceled(t *testing.T) {
+	var logs []string
+	ch := make(chan string, 1)
+	stream := WithLog(FromChan(ch), "test", func(info string) { logs = append(logs, info) })
+
+	expectCanceledPromptly(t, stream)
+	ch <- "a"
+	close(ch)
+	expectStringStream(t, stream, "a", io.EOF)
+	if len(logs) != 2 || !strings.Contains(logs[1], "consume completed") || !strings.Contains(logs[1], "1 items") {
+		t.Fatalf("logs = %q", logs)
+	}
+}
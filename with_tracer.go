@@ -1,6 +1,7 @@
 package streams
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -43,7 +44,24 @@ func (s *streamWithTracer[T]) finish(err error) {
 	s.span.Finish()
 }
 
+// Close 先关闭上游(不加锁，避免被阻塞中的Recv卡住)，如果流还没有消费完，以ErrClosed结束span
+func (s *streamWithTracer[T]) Close() error {
+	err := Close(s.Stream)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.startAt != nil && !s.stop {
+		s.finish(ErrClosed)
+	}
+	s.stop = true
+	return err
+}
+
 func (s *streamWithTracer[T]) Recv() (T, error) {
+	return s.RecvContext(context.Background())
+}
+
+func (s *streamWithTracer[T]) RecvContext(ctx context.Context) (T, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -52,7 +70,10 @@ func (s *streamWithTracer[T]) Recv() (T, error) {
 		s.startAt = &now
 	}
 
-	c, err := s.Stream.Recv()
+	c, err := RecvContext(ctx, s.Stream)
+	if isContextErr(ctx, err) { // ctx取消只影响本次调用，换一个ctx还可以继续读取
+		return c, err
+	}
 
 	if s.firstTokenAt == nil {
 		now := time.Now()
@@ -0,0 +1,32 @@
+package streams
+
+import (
+	"io"
+	"testing"
+)
+
+type recordSpan struct {
+	output   any
+	finished int
+}
+
+func (s *recordSpan) SetOutput(output any) { s.output = output }
+func (s *recordSpan) Finish()              { s.finished++ }
+
+func TestWithTracer_ContextCanceled(t *testing.T) {
+	span := &recordSpan{}
+	ch := make(chan string, 1)
+	stream := WithTracer(FromChan(ch), span)
+
+	expectCanceledPromptly(t, stream)
+	if span.finished != 0 {
+		t.Fatal("span finished on ctx cancel")
+	}
+	ch <- "a"
+	close(ch)
+	expectStringStream(t, stream, "a", io.EOF)
+	output, _ := span.output.(map[string]any)
+	if span.finished != 1 || output["error"] != io.EOF || output["frames_count"] != 1 {
+		t.Fatalf("span = %+v", span)
+	}
+}